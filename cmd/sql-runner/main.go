@@ -1,13 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/iyuangang/oracle-sql-runner/internal/config"
 	"github.com/iyuangang/oracle-sql-runner/internal/core"
 	"github.com/iyuangang/oracle-sql-runner/internal/utils"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -18,11 +24,21 @@ var (
 	BuildTime = "unknown"
 
 	// 命令行参数
-	configFile string
-	sqlFile    string
-	dbName     string
-	verbose    bool
-	osExit     = os.Exit
+	configFile    string
+	sqlFile       string
+	dbName        string
+	verbose       bool
+	defines       []string
+	statements    []string
+	mode          string
+	txMode        string
+	format        string
+	spoolDir      string
+	dbConcurrency int
+	waveSpecs     string
+	wavePause     int
+	confirmWaves  bool
+	osExit        = os.Exit
 )
 
 // setupLogger 初始化日志记录器
@@ -37,12 +53,8 @@ func setupLogger(cfg *config.Config, configDir string) (*utils.Logger, error) {
 // handleDatabasePasswords 处理数据库密码的加密和解密
 func handleDatabasePasswords(cfg *config.Config, configPath string) error {
 	configModified := false
-	memoryConfig := &config.Config{
-		Databases:     make(map[string]config.DatabaseConfig),
-		MaxConcurrent: cfg.MaxConcurrent,
-		LogFile:       cfg.LogFile,
-		LogLevel:      cfg.LogLevel,
-	}
+	memoryConfig := *cfg
+	memoryConfig.Databases = make(map[string]config.DatabaseConfig, len(cfg.Databases))
 
 	// 处理所有数据库的密码
 	for name, dbConfig := range cfg.Databases {
@@ -75,37 +87,155 @@ func handleDatabasePasswords(cfg *config.Config, configPath string) error {
 		if err := config.Save(configPath, cfg); err != nil {
 			return fmt.Errorf("保存加密后的配置失败: %w", err)
 		}
-		if logger, err := utils.NewLogger("sql-runner.log", "info", verbose); err == nil {
+		// 日志写入配置文件所在目录，而不是当前工作目录
+		logFile := filepath.Join(filepath.Dir(configPath), "sql-runner.log")
+		if logger, err := utils.NewLogger(logFile, "info", verbose); err == nil {
 			logger.Info("数据库密码已加密并保存到配置文件")
 			logger.Close()
 		}
 	}
 
 	// 用解密后的配置替换原配置
-	*cfg = *memoryConfig
+	*cfg = memoryConfig
+	return nil
+}
+
+// applyDefines 将命令行指定的替换变量（name=value）合并到各数据库配置，覆盖同名变量
+func applyDefines(cfg *config.Config, defines []string) error {
+	if len(defines) == 0 {
+		return nil
+	}
+
+	values := make(map[string]string, len(defines))
+	for _, define := range defines {
+		name, value, ok := strings.Cut(define, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return fmt.Errorf("无效的替换变量定义: %s，格式应为 name=value", define)
+		}
+		values[name] = value
+	}
+
+	for dbName, dbConfig := range cfg.Databases {
+		merged := make(map[string]string, len(dbConfig.Defines)+len(values))
+		for name, value := range dbConfig.Defines {
+			merged[strings.ToUpper(name)] = value
+		}
+		for name, value := range values {
+			merged[strings.ToUpper(name)] = value
+		}
+		dbConfig.Defines = merged
+		cfg.Databases[dbName] = dbConfig
+	}
+	return nil
+}
+
+// applyMode 使用命令行指定的执行模式覆盖配置
+func applyMode(cfg *config.Config, mode string) error {
+	if mode == "" {
+		return nil
+	}
+	if err := config.ValidateMode(mode); err != nil {
+		return err
+	}
+	cfg.Mode = mode
+	return nil
+}
+
+// applyTx 使用命令行指定的事务模式覆盖配置
+func applyTx(cfg *config.Config, tx string) error {
+	if tx == "" {
+		return nil
+	}
+	if err := config.ValidateTx(tx); err != nil {
+		return err
+	}
+	cfg.Tx = tx
+	return nil
+}
+
+// applyFormat 使用命令行指定的输出格式覆盖配置
+func applyFormat(cfg *config.Config, format string) error {
+	if format == "" {
+		return nil
+	}
+	if err := config.ValidateFormat(format); err != nil {
+		return err
+	}
+	cfg.Format = format
 	return nil
 }
 
+// exitCodeError 携带脚本指定退出码的错误
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("脚本退出, 退出码: %d", e.code)
+}
+
+// 标准输入和 -e 语句在日志及错误信息中使用的名称
+const (
+	stdinName  = "<stdin>"
+	inlineName = "<inline>"
+)
+
 // validateInputs 验证输入参数
-func validateInputs(sqlFile, dbName string) error {
-	if sqlFile == "" {
-		return fmt.Errorf("请指定SQL文件路径 (-f)")
+func validateInputs(sqlFile string, statements []string, dbName string) error {
+	if sqlFile == "" && len(statements) == 0 {
+		return fmt.Errorf("请指定SQL文件路径 (-f) 或SQL语句 (-e)")
+	}
+	if sqlFile != "" && len(statements) > 0 {
+		return fmt.Errorf("不能同时指定 -f 和 -e")
 	}
 	if dbName == "" {
 		return fmt.Errorf("请指定数据库名称 (-d)")
 	}
+	if sqlFile == "" || sqlFile == "-" {
+		return nil
+	}
 	if _, err := os.Stat(sqlFile); os.IsNotExist(err) {
 		return fmt.Errorf("SQL文件不存在: %s", sqlFile)
 	}
 	return nil
 }
 
-// runSQL 执行SQL文件
-func runSQL(cfg *config.Config, dbName, sqlFile string, logger *utils.Logger) error {
-	// 检查数据库配置是否存在
-	if _, ok := cfg.Databases[dbName]; !ok {
-		return fmt.Errorf("数据库 %s 未配置", dbName)
+// inlineScript 将 -e 指定的语句拼接为脚本，每条语句后追加 / 以便同样支持 PL/SQL 块
+func inlineScript(statements []string) string {
+	var sb strings.Builder
+	for _, stmt := range statements {
+		sb.WriteString(stmt)
+		sb.WriteString("\n/\n")
+	}
+	return sb.String()
+}
+
+// openScript 根据 -f/-e 参数打开脚本，返回脚本内容和名称
+func openScript(sqlFile string, statements []string) (io.ReadCloser, string, error) {
+	switch {
+	case len(statements) > 0:
+		return io.NopCloser(strings.NewReader(inlineScript(statements))), inlineName, nil
+	case sqlFile == "-":
+		return io.NopCloser(os.Stdin), stdinName, nil
 	}
+	file, err := os.Open(sqlFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("打开SQL文件失败: %w", err)
+	}
+	return file, sqlFile, nil
+}
+
+// runSQL 执行SQL文件、标准输入或 -e 指定的语句，-d 选择多个数据库时在每个数据库上执行
+func runSQL(cfg *config.Config, dbName, sqlFile string, statements []string, logger *utils.Logger) error {
+	names, err := cfg.SelectDatabases(dbName)
+	if err != nil {
+		return err
+	}
+	if len(names) > 1 {
+		return runDatabases(cfg, names, sqlFile, statements, logger)
+	}
+	dbName = names[0]
 
 	// 创建执行器
 	executor, err := core.NewExecutor(cfg, dbName, logger)
@@ -114,25 +244,143 @@ func runSQL(cfg *config.Config, dbName, sqlFile string, logger *utils.Logger) er
 	}
 	defer executor.Close()
 
+	script, name, err := openScript(sqlFile, statements)
+	if err != nil {
+		return err
+	}
+	defer script.Close()
+
 	defer fmt.Println()
 
-	// 执行SQL文件
-	result := executor.ExecuteFile(sqlFile)
+	// 执行SQL脚本
+	result := executor.ExecuteReader(script, name)
 	result.Print()
 
 	// 强制刷新输出
 	os.Stdout.Sync()
 
+	return resultError(result)
+}
+
+// resultError 根据执行结果返回错误
+//
+// 脚本通过 WHENEVER/EXIT 以非 0 退出码结束时使用其指定的退出码；退出码为 0 时
+// （如 WHENEVER SQLERROR CONTINUE 之后的 EXIT）仍按失败的语句判断。
+func resultError(result *models.Result) error {
+	if result.Exited && result.ExitCode != 0 {
+		return &exitCodeError{code: result.ExitCode}
+	}
 	if result.Failed > 0 {
 		return fmt.Errorf("执行失败")
 	}
 	return nil
 }
 
+// runDatabases 在多个数据库上执行同一脚本并打印汇总结果
+func runDatabases(cfg *config.Config, names []string, sqlFile string, statements []string, logger *utils.Logger) error {
+	if cfg.Rollout.Confirm && sqlFile == "-" {
+		return fmt.Errorf("从标准输入读取脚本时无法确认批次")
+	}
+
+	// 脚本需要在每个数据库上重新解析，先完整读入
+	script, name, err := openScript(sqlFile, statements)
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(script)
+	script.Close()
+	if err != nil {
+		return fmt.Errorf("读取SQL脚本失败: %w", err)
+	}
+
+	defer fmt.Println()
+
+	waves, err := cfg.PlanWaves(names)
+	if err != nil {
+		return err
+	}
+	gate := waveGate(cfg.Rollout, os.Stdin, os.Stdout)
+
+	report := core.ExecuteWaves(cfg, waves, content, name, logger, os.Stdout, gate)
+	report.Print()
+
+	// 强制刷新输出
+	os.Stdout.Sync()
+
+	failed := report.Failed()
+	if len(failed) == 0 {
+		if len(report.Pending) > 0 {
+			return fmt.Errorf("已取消，%d 个数据库未执行", len(report.Pending))
+		}
+		return nil
+	}
+	if code := report.ExitCode(); code != 1 {
+		return &exitCodeError{code: code}
+	}
+	return fmt.Errorf("%d 个数据库执行失败: %s", len(failed), strings.Join(failed, ", "))
+}
+
+// waveGate 返回每批开始前按配置等待并确认的回调，都未配置时返回 nil
+func waveGate(rollout config.RolloutConfig, in io.Reader, out io.Writer) core.WaveGate {
+	if rollout.Pause <= 0 && !rollout.Confirm {
+		return nil
+	}
+	reader := bufio.NewReader(in)
+	return func(wave int, names []string) bool {
+		if rollout.Pause > 0 {
+			fmt.Fprintf(out, "等待 %d 秒后执行第 %d 批...\n", rollout.Pause, wave+1)
+			time.Sleep(time.Duration(rollout.Pause) * time.Second)
+		}
+		if !rollout.Confirm {
+			return true
+		}
+		fmt.Fprintf(out, "是否继续执行第 %d 批（%s）？[y/N] ", wave+1, strings.Join(names, ", "))
+		answer, _ := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		}
+		return false
+	}
+}
+
+// applyRollout 使用命令行指定的分批策略覆盖配置，waves 为逗号分隔的批次
+func applyRollout(cfg *config.Config, waves string, pause int, confirm bool) error {
+	if waves != "" {
+		specs := strings.Split(waves, ",")
+		if err := config.ValidateWaves(specs); err != nil {
+			return err
+		}
+		cfg.Rollout.Waves = specs
+	}
+	if pause < 0 {
+		return fmt.Errorf("无效的批次等待时间: %d", pause)
+	}
+	if pause > 0 {
+		cfg.Rollout.Pause = pause
+	}
+	if confirm {
+		cfg.Rollout.Confirm = true
+	}
+	return nil
+}
+
+// applyDBConcurrency 使用命令行指定的数据库并发数覆盖配置
+func applyDBConcurrency(cfg *config.Config, n int) error {
+	if n == 0 {
+		return nil
+	}
+	if n < 0 {
+		return fmt.Errorf("无效的数据库并发数: %d", n)
+	}
+	cfg.DBConcurrency = n
+	return nil
+}
+
 // run 主要执行逻辑
 func run(cmd *cobra.Command, args []string) error {
 	// 验证输入参数
-	if err := validateInputs(sqlFile, dbName); err != nil {
+	if err := validateInputs(sqlFile, statements, dbName); err != nil {
 		return err
 	}
 
@@ -147,6 +395,31 @@ func run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// 合并命令行替换变量
+	if err := applyDefines(cfg, defines); err != nil {
+		return err
+	}
+
+	// 命令行指定的执行模式和事务模式优先于配置文件
+	if err := applyMode(cfg, mode); err != nil {
+		return err
+	}
+	if err := applyTx(cfg, txMode); err != nil {
+		return err
+	}
+	if err := applyFormat(cfg, format); err != nil {
+		return err
+	}
+	if spoolDir != "" {
+		cfg.SpoolDir = spoolDir
+	}
+	if err := applyDBConcurrency(cfg, dbConcurrency); err != nil {
+		return err
+	}
+	if err := applyRollout(cfg, waveSpecs, wavePause, confirmWaves); err != nil {
+		return err
+	}
+
 	// 设置日志记录器
 	logger, err := setupLogger(cfg, filepath.Dir(configFile))
 	if err != nil {
@@ -160,10 +433,17 @@ func run(cmd *cobra.Command, args []string) error {
 		"build_time", BuildTime,
 		"config", configFile,
 		"sql_file", sqlFile,
-		"database", dbName)
+		"statements", len(statements),
+		"database", dbName,
+		"mode", cfg.Mode,
+		"tx", cfg.Tx,
+		"format", cfg.Format,
+		"spool_dir", cfg.SpoolDir,
+		"db_concurrency", cfg.DBConcurrency,
+		"waves", cfg.Rollout.Waves)
 
 	// 执行SQL文件
-	return runSQL(cfg, dbName, sqlFile, logger)
+	return runSQL(cfg, dbName, sqlFile, statements, logger)
 }
 
 func main() {
@@ -180,9 +460,19 @@ func main() {
 
 	// 设置命令行参数
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "config.json", "配置文件路径")
-	rootCmd.PersistentFlags().StringVarP(&sqlFile, "file", "f", "", "SQL文件路径")
-	rootCmd.PersistentFlags().StringVarP(&dbName, "database", "d", "", "数据库名称")
+	rootCmd.PersistentFlags().StringVarP(&sqlFile, "file", "f", "", "SQL文件路径，- 表示从标准输入读取")
+	rootCmd.PersistentFlags().StringArrayVarP(&statements, "execute", "e", nil, "直接执行的SQL语句，可重复指定")
+	rootCmd.PersistentFlags().StringVarP(&dbName, "database", "d", "", "数据库名称，可用逗号分隔多个名称、通配符模式或 tag:标签")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "显示详细信息")
+	rootCmd.PersistentFlags().StringArrayVarP(&defines, "define", "D", nil, "替换变量 name=value，可重复指定")
+	rootCmd.PersistentFlags().StringVar(&mode, "mode", "", "执行模式: serial 按脚本顺序执行（默认），parallel 并行执行，dag 按依赖关系并行执行")
+	rootCmd.PersistentFlags().StringVar(&txMode, "tx", "", "事务模式: auto 自动提交（默认），file 整个脚本一个事务，statement 每条DML使用保存点")
+	rootCmd.PersistentFlags().StringVar(&format, "format", "", "查询结果输出格式: table（默认）、vertical、csv、json、ndjson、markdown、html")
+	rootCmd.PersistentFlags().StringVar(&spoolDir, "spool-dir", "", "将每条查询的结果按输出格式写入该目录下的单独文件")
+	rootCmd.PersistentFlags().IntVar(&dbConcurrency, "db-concurrency", 0, "选择多个数据库时同时执行的数据库数，默认使用配置中的 db_concurrency")
+	rootCmd.PersistentFlags().StringVar(&waveSpecs, "waves", "", "分批执行多个数据库，逗号分隔每批的数量、百分比或选择器，如 1,10%，剩余数据库作为最后一批")
+	rootCmd.PersistentFlags().IntVar(&wavePause, "wave-pause", 0, "每批开始前等待的秒数")
+	rootCmd.PersistentFlags().BoolVar(&confirmWaves, "confirm-waves", false, "每批开始前需要确认")
 
 	// 加密命令
 	var encryptPassword string
@@ -227,6 +517,11 @@ func main() {
 	// 执行命令
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			osExit(exitErr.code)
+			return
+		}
 		osExit(1)
 	}
 }
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/iyuangang/oracle-sql-runner/internal/config"
+	"github.com/iyuangang/oracle-sql-runner/internal/core"
 	"github.com/iyuangang/oracle-sql-runner/internal/utils"
+	"github.com/iyuangang/oracle-sql-runner/pkg/formatter"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -108,6 +114,22 @@ func TestHandleDatabasePasswords(t *testing.T) {
 				assert.False(t, utils.IsEncrypted(cfg.Databases["db2"].Password))
 			},
 		},
+		{
+			name: "保留其他配置项",
+			config: &config.Config{
+				Databases: map[string]config.DatabaseConfig{
+					"test": {Password: "test123", Defines: map[string]string{"SCHEMA": "app"}},
+				},
+				MaxRetries: new(int),
+				Timeout:    60,
+			},
+			wantErr: false,
+			checkFunc: func(t *testing.T, cfg *config.Config) {
+				assert.Equal(t, 0, cfg.Retries())
+				assert.Equal(t, 60, cfg.Timeout)
+				assert.Equal(t, "app", cfg.Databases["test"].Defines["SCHEMA"])
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -123,6 +145,141 @@ func TestHandleDatabasePasswords(t *testing.T) {
 			}
 		})
 	}
+
+	// 加密密码的日志写入配置文件所在目录
+	assert.FileExists(t, filepath.Join(tmpDir, "sql-runner.log"))
+}
+
+func TestApplyDefines(t *testing.T) {
+	cfg := &config.Config{
+		Databases: map[string]config.DatabaseConfig{
+			"dev":  {Defines: map[string]string{"schema": "app_dev", "tbs": "USERS"}},
+			"prod": {},
+		},
+	}
+
+	require.NoError(t, applyDefines(cfg, []string{"schema=app", "owner=a=b"}))
+	assert.Equal(t, map[string]string{"SCHEMA": "app", "TBS": "USERS", "OWNER": "a=b"}, cfg.Databases["dev"].Defines)
+	assert.Equal(t, map[string]string{"SCHEMA": "app", "OWNER": "a=b"}, cfg.Databases["prod"].Defines)
+
+	assert.Error(t, applyDefines(cfg, []string{"schema"}))
+	assert.Error(t, applyDefines(cfg, []string{"=app"}))
+}
+
+func TestApplyMode(t *testing.T) {
+	cfg := &config.Config{Mode: config.ModeSerial}
+
+	require.NoError(t, applyMode(cfg, ""))
+	assert.Equal(t, config.ModeSerial, cfg.Mode)
+
+	require.NoError(t, applyMode(cfg, config.ModeParallel))
+	assert.Equal(t, config.ModeParallel, cfg.Mode)
+
+	require.NoError(t, applyMode(cfg, config.ModeDAG))
+	assert.Equal(t, config.ModeDAG, cfg.Mode)
+
+	assert.Error(t, applyMode(cfg, "random"))
+	assert.Equal(t, config.ModeDAG, cfg.Mode)
+}
+
+func TestApplyTx(t *testing.T) {
+	cfg := &config.Config{Tx: config.TxAuto}
+
+	require.NoError(t, applyTx(cfg, ""))
+	assert.Equal(t, config.TxAuto, cfg.Tx)
+
+	require.NoError(t, applyTx(cfg, config.TxFile))
+	assert.Equal(t, config.TxFile, cfg.Tx)
+
+	assert.Error(t, applyTx(cfg, "manual"))
+	assert.Equal(t, config.TxFile, cfg.Tx)
+}
+
+func TestApplyFormat(t *testing.T) {
+	cfg := &config.Config{Format: formatter.FormatTable}
+
+	require.NoError(t, applyFormat(cfg, ""))
+	assert.Equal(t, formatter.FormatTable, cfg.Format)
+
+	require.NoError(t, applyFormat(cfg, formatter.FormatCSV))
+	assert.Equal(t, formatter.FormatCSV, cfg.Format)
+
+	assert.Error(t, applyFormat(cfg, "xml"))
+	assert.Equal(t, formatter.FormatCSV, cfg.Format)
+}
+
+func TestResultError(t *testing.T) {
+	tests := []struct {
+		name     string
+		failed   bool
+		exited   bool
+		exitCode int
+		wantErr  bool
+		wantCode int
+	}{
+		{name: "全部成功"},
+		{name: "语句失败", failed: true, wantErr: true},
+		{name: "EXIT 0", exited: true},
+		{name: "语句失败后 EXIT 0", failed: true, exited: true, wantErr: true},
+		{name: "指定退出码", failed: true, exited: true, exitCode: 3, wantErr: true, wantCode: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := models.NewResult()
+			if tt.failed {
+				result.AddError(models.SQLTask{SQL: "DROP TABLE missing"}, errors.New("ORA-00942: table or view does not exist"))
+			}
+			result.Exited = tt.exited
+			result.ExitCode = tt.exitCode
+
+			err := resultError(result)
+			assert.Equal(t, tt.wantErr, err != nil)
+			var exitErr *exitCodeError
+			if errors.As(err, &exitErr) {
+				assert.Equal(t, tt.wantCode, exitErr.code)
+			} else {
+				assert.Zero(t, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestApplyDBConcurrency(t *testing.T) {
+	cfg := &config.Config{DBConcurrency: 1}
+
+	require.NoError(t, applyDBConcurrency(cfg, 0))
+	assert.Equal(t, 1, cfg.DBConcurrency)
+
+	require.NoError(t, applyDBConcurrency(cfg, 4))
+	assert.Equal(t, 4, cfg.DBConcurrency)
+
+	assert.Error(t, applyDBConcurrency(cfg, -1))
+	assert.Equal(t, 4, cfg.DBConcurrency)
+}
+
+func TestApplyRollout(t *testing.T) {
+	cfg := &config.Config{}
+
+	require.NoError(t, applyRollout(cfg, "", 0, false))
+	assert.Equal(t, config.RolloutConfig{}, cfg.Rollout)
+
+	require.NoError(t, applyRollout(cfg, "tag:canary,10%", 30, true))
+	assert.Equal(t, config.RolloutConfig{Waves: []string{"tag:canary", "10%"}, Pause: 30, Confirm: true}, cfg.Rollout)
+
+	assert.Error(t, applyRollout(cfg, "1,200%", 0, false))
+	assert.Error(t, applyRollout(cfg, "", -1, false))
+}
+
+func TestWaveGate(t *testing.T) {
+	assert.Nil(t, waveGate(config.RolloutConfig{}, strings.NewReader(""), io.Discard))
+
+	var out bytes.Buffer
+	gate := waveGate(config.RolloutConfig{Confirm: true}, strings.NewReader("y\nno\n"), &out)
+	require.NotNil(t, gate)
+	assert.True(t, gate(1, []string{"db1", "db2"}))
+	assert.Contains(t, out.String(), "是否继续执行第 2 批（db1, db2）？[y/N] ")
+	assert.False(t, gate(2, []string{"db3"}))
+	assert.False(t, gate(3, []string{"db4"}), "输入结束时不继续")
 }
 
 func TestValidateInputs(t *testing.T) {
@@ -131,10 +288,11 @@ func TestValidateInputs(t *testing.T) {
 	require.NoError(t, os.WriteFile(validFile, []byte("SELECT 1 FROM DUAL;"), 0o644))
 
 	tests := []struct {
-		name    string
-		sqlFile string
-		dbName  string
-		wantErr bool
+		name       string
+		sqlFile    string
+		statements []string
+		dbName     string
+		wantErr    bool
 	}{
 		{
 			name:    "有效输入",
@@ -142,6 +300,25 @@ func TestValidateInputs(t *testing.T) {
 			dbName:  "test",
 			wantErr: false,
 		},
+		{
+			name:    "标准输入",
+			sqlFile: "-",
+			dbName:  "test",
+			wantErr: false,
+		},
+		{
+			name:       "直接执行语句",
+			statements: []string{"SELECT 1 FROM DUAL"},
+			dbName:     "test",
+			wantErr:    false,
+		},
+		{
+			name:       "同时指定文件和语句",
+			sqlFile:    validFile,
+			statements: []string{"SELECT 1 FROM DUAL"},
+			dbName:     "test",
+			wantErr:    true,
+		},
 		{
 			name:    "缺少SQL文件",
 			sqlFile: "",
@@ -164,7 +341,7 @@ func TestValidateInputs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateInputs(tt.sqlFile, tt.dbName)
+			err := validateInputs(tt.sqlFile, tt.statements, tt.dbName)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -174,6 +351,36 @@ func TestValidateInputs(t *testing.T) {
 	}
 }
 
+func TestOpenScript(t *testing.T) {
+	t.Run("直接执行语句", func(t *testing.T) {
+		script, name, err := openScript("", []string{"SELECT 1 FROM DUAL", "BEGIN NULL; END;"})
+		require.NoError(t, err)
+		defer script.Close()
+		assert.Equal(t, inlineName, name)
+
+		parser := core.ParseReader(script, core.ParseOptions{Filename: name})
+		defer parser.Close()
+		var sqls []string
+		for parser.Next() {
+			sqls = append(sqls, parser.Task().SQL)
+		}
+		require.NoError(t, parser.Err())
+		assert.Equal(t, []string{"SELECT 1 FROM DUAL", "BEGIN NULL; END;"}, sqls)
+	})
+
+	t.Run("标准输入", func(t *testing.T) {
+		script, name, err := openScript("-", nil)
+		require.NoError(t, err)
+		assert.Equal(t, stdinName, name)
+		assert.NoError(t, script.Close())
+	})
+
+	t.Run("文件不存在", func(t *testing.T) {
+		_, _, err := openScript(filepath.Join(t.TempDir(), "missing.sql"), nil)
+		assert.Error(t, err)
+	})
+}
+
 // 添加命令初始化函数
 func initCommands() {
 	rootCmd = &cobra.Command{
@@ -185,9 +392,19 @@ func initCommands() {
 
 	// 设置命令行参数
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "config.json", "配置文件路径")
-	rootCmd.PersistentFlags().StringVarP(&sqlFile, "file", "f", "", "SQL文件路径")
-	rootCmd.PersistentFlags().StringVarP(&dbName, "database", "d", "", "数据库名称")
+	rootCmd.PersistentFlags().StringVarP(&sqlFile, "file", "f", "", "SQL文件路径，- 表示从标准输入读取")
+	rootCmd.PersistentFlags().StringArrayVarP(&statements, "execute", "e", nil, "直接执行的SQL语句，可重复指定")
+	rootCmd.PersistentFlags().StringVarP(&dbName, "database", "d", "", "数据库名称，可用逗号分隔多个名称、通配符模式或 tag:标签")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "显示详细信息")
+	rootCmd.PersistentFlags().StringArrayVarP(&defines, "define", "D", nil, "替换变量 name=value，可重复指定")
+	rootCmd.PersistentFlags().StringVar(&mode, "mode", "", "执行模式: serial 按脚本顺序执行（默认），parallel 并行执行，dag 按依赖关系并行执行")
+	rootCmd.PersistentFlags().StringVar(&txMode, "tx", "", "事务模式: auto 自动提交（默认），file 整个脚本一个事务，statement 每条DML使用保存点")
+	rootCmd.PersistentFlags().StringVar(&format, "format", "", "查询结果输出格式: table（默认）、vertical、csv、json、ndjson、markdown、html")
+	rootCmd.PersistentFlags().StringVar(&spoolDir, "spool-dir", "", "将每条查询的结果按输出格式写入该目录下的单独文件")
+	rootCmd.PersistentFlags().IntVar(&dbConcurrency, "db-concurrency", 0, "选择多个数据库时同时执行的数据库数，默认使用配置中的 db_concurrency")
+	rootCmd.PersistentFlags().StringVar(&waveSpecs, "waves", "", "分批执行多个数据库，逗号分隔每批的数量、百分比或选择器，如 1,10%，剩余数据库作为最后一批")
+	rootCmd.PersistentFlags().IntVar(&wavePause, "wave-pause", 0, "每批开始前等待的秒数")
+	rootCmd.PersistentFlags().BoolVar(&confirmWaves, "confirm-waves", false, "每批开始前需要确认")
 
 	// 加密命令
 	var encryptPassword string
@@ -429,7 +646,7 @@ func TestRunSQL(t *testing.T) {
 						}
 					}
 				}()
-				err = runSQL(cfg, tt.dbName, tt.sqlFile, logger)
+				err = runSQL(cfg, tt.dbName, tt.sqlFile, nil, logger)
 			}()
 
 			if tt.wantErr {
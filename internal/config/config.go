@@ -4,8 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/formatter"
 )
 
 // DatabaseConfig 数据库配置
@@ -18,15 +23,67 @@ type DatabaseConfig struct {
 	Service        string        `json:"service"`
 	MaxConnections int           `json:"max_connections"`
 	IdleTimeout    time.Duration `json:"idle_timeout"`
+	// Defines 执行脚本时使用的替换变量（&name）
+	Defines map[string]string `json:"defines,omitempty"`
+	// Tags 数据库标签，-d tag:name 选择带有该标签的所有数据库
+	Tags []string `json:"tags,omitempty"`
+}
+
+// 执行模式
+const (
+	ModeSerial   = "serial"   // 按脚本顺序逐条执行
+	ModeParallel = "parallel" // 命令之间的语句并行执行，适用于相互独立的查询
+	ModeDAG      = "dag"      // 按语句读写的对象分析依赖，只并行执行相互独立的语句
+)
+
+// 事务模式
+const (
+	TxAuto      = "auto"      // 每条语句自动提交
+	TxFile      = "file"      // 整个脚本在一个事务中执行，出错时回滚
+	TxStatement = "statement" // 整个脚本在一个事务中执行，每条DML出错时回滚到执行前的保存点
+)
+
+// RetryConfig 可重试错误的退避策略
+type RetryConfig struct {
+	BaseDelay  int   `json:"base_delay_ms"`   // 第一次重试前的等待时间（毫秒），之后每次翻倍
+	MaxDelay   int   `json:"max_delay_ms"`    // 单次等待时间上限（毫秒）
+	MaxElapsed int   `json:"max_elapsed"`     // 从首次执行起允许重试的总时长（秒）
+	Codes      []int `json:"codes,omitempty"` // 额外视为可重试的 Oracle 错误码，如 60 表示 ORA-00060
+}
+
+// DisplayConfig 查询结果中值的显示方式，日期时间格式使用 Go 的时间格式，如 2006-01-02 15:04:05
+type DisplayConfig struct {
+	DateFormat        string  `json:"date_format,omitempty"`         // DATE 的显示格式
+	TimestampFormat   string  `json:"timestamp_format,omitempty"`    // TIMESTAMP 的显示格式
+	TimestampTZFormat string  `json:"timestamp_tz_format,omitempty"` // TIMESTAMP WITH [LOCAL] TIME ZONE 的显示格式
+	Null              *string `json:"null,omitempty"`                // NULL 的显示，未配置时显示为 NULL
+}
+
+// RolloutConfig 多数据库执行时的分批策略
+type RolloutConfig struct {
+	Waves   []string `json:"waves,omitempty"` // 每批的数据库，可以是数量、百分比或选择器，未分配的数据库作为最后一批
+	Pause   int      `json:"pause"`           // 每批开始前的等待时间（秒）
+	Confirm bool     `json:"confirm"`         // 每批开始前是否需要确认
 }
 
 // Config 全局配置
 type Config struct {
 	Databases     map[string]DatabaseConfig `json:"databases"`
-	MaxRetries    int                       `json:"max_retries"`
+	MaxRetries    *int                      `json:"max_retries,omitempty"` // 可重试错误在首次执行之后的最大重试次数，未配置时为 3，0 表示不重试
+	Retry         RetryConfig               `json:"retry"`
 	MaxConcurrent int                       `json:"max_concurrent"`
-	BatchSize     int                       `json:"batch_size"`
+	DBConcurrency int                       `json:"db_concurrency"` // 同时执行脚本的数据库数
+	Rollout       RolloutConfig             `json:"rollout"`
+	BatchSize     int                       `json:"batch_size"`    // 查询每次从数据库获取的行数
+	PrefetchRows  int                       `json:"prefetch_rows"` // 执行查询时预取的行数，0 表示使用驱动默认值
+	MaxRows       int                       `json:"max_rows"`      // 每个查询最多输出的行数，0 表示不限制
+	LOBLimit      int                       `json:"lob_limit"`     // 每个 LOB 值最多读取的字节数，-1 表示不限制
 	Timeout       int                       `json:"timeout"`
+	Mode          string                    `json:"mode,omitempty"`
+	Tx            string                    `json:"tx,omitempty"`
+	Format        string                    `json:"format,omitempty"`
+	SpoolDir      string                    `json:"spool_dir,omitempty"` // 每条查询的结果写入该目录下的单独文件
+	Display       DisplayConfig             `json:"display"`
 	LogLevel      string                    `json:"log_level"`
 	LogFile       string                    `json:"log_file"`
 }
@@ -59,18 +116,39 @@ func Load(path string) (*Config, error) {
 	}
 
 	// 设置默认值
-	if cfg.MaxRetries == 0 {
-		cfg.MaxRetries = 3
+	if cfg.Retry.BaseDelay == 0 {
+		cfg.Retry.BaseDelay = 100
+	}
+	if cfg.Retry.MaxDelay == 0 {
+		cfg.Retry.MaxDelay = 5000
+	}
+	if cfg.Retry.MaxElapsed == 0 {
+		cfg.Retry.MaxElapsed = 60
 	}
 	if cfg.MaxConcurrent == 0 {
 		cfg.MaxConcurrent = 5
 	}
+	if cfg.DBConcurrency == 0 {
+		cfg.DBConcurrency = 1
+	}
 	if cfg.BatchSize == 0 {
 		cfg.BatchSize = 1000
 	}
+	if cfg.LOBLimit == 0 {
+		cfg.LOBLimit = 65536
+	}
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 30
 	}
+	if cfg.Mode == "" {
+		cfg.Mode = ModeSerial
+	}
+	if cfg.Tx == "" {
+		cfg.Tx = TxAuto
+	}
+	if cfg.Format == "" {
+		cfg.Format = formatter.FormatTable
+	}
 
 	return &cfg, validate(&cfg)
 }
@@ -80,6 +158,40 @@ func validate(cfg *Config) error {
 	if len(cfg.Databases) == 0 {
 		return fmt.Errorf("至少需要配置一个数据库")
 	}
+	if err := ValidateMode(cfg.Mode); err != nil {
+		return err
+	}
+	if err := ValidateTx(cfg.Tx); err != nil {
+		return err
+	}
+	if err := ValidateFormat(cfg.Format); err != nil {
+		return err
+	}
+	if cfg.MaxRetries != nil && *cfg.MaxRetries < 0 {
+		return fmt.Errorf("无效的最大重试次数: %d", *cfg.MaxRetries)
+	}
+	if cfg.BatchSize < 0 || cfg.PrefetchRows < 0 {
+		return fmt.Errorf("无效的获取行数: batch_size=%d, prefetch_rows=%d", cfg.BatchSize, cfg.PrefetchRows)
+	}
+	if cfg.DBConcurrency < 0 {
+		return fmt.Errorf("无效的数据库并发数: %d", cfg.DBConcurrency)
+	}
+	if err := ValidateWaves(cfg.Rollout.Waves); err != nil {
+		return err
+	}
+	if cfg.Rollout.Pause < 0 {
+		return fmt.Errorf("无效的批次等待时间: %d", cfg.Rollout.Pause)
+	}
+	if cfg.MaxRows < 0 {
+		return fmt.Errorf("无效的最大行数: %d", cfg.MaxRows)
+	}
+	if cfg.LOBLimit < -1 {
+		return fmt.Errorf("无效的LOB读取限制: %d", cfg.LOBLimit)
+	}
+	if cfg.Retry.BaseDelay < 0 || cfg.Retry.MaxDelay < cfg.Retry.BaseDelay || cfg.Retry.MaxElapsed < 0 {
+		return fmt.Errorf("无效的重试配置: base_delay_ms=%d, max_delay_ms=%d, max_elapsed=%d",
+			cfg.Retry.BaseDelay, cfg.Retry.MaxDelay, cfg.Retry.MaxElapsed)
+	}
 
 	for name, db := range cfg.Databases {
 		if db.User == "" {
@@ -102,6 +214,198 @@ func validate(cfg *Config) error {
 	return nil
 }
 
+// defaultMaxRetries 未配置 max_retries 时的最大重试次数
+const defaultMaxRetries = 3
+
+// Retries 返回可重试错误在首次执行之后的最大重试次数
+func (c *Config) Retries() int {
+	if c.MaxRetries == nil {
+		return defaultMaxRetries
+	}
+	return *c.MaxRetries
+}
+
+// tagPrefix 按标签选择数据库的前缀，如 tag:prod
+const tagPrefix = "tag:"
+
+// SelectDatabases 返回选择器匹配的数据库名称，按名称排序
+//
+// 选择器由逗号分隔，每一项可以是数据库名称、通配符模式（如 prod-*）或 tag:标签，
+// 结果为各项匹配结果的并集。名称不存在或某一项没有匹配任何数据库时返回错误。
+func (c *Config) SelectDatabases(selector string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		matched := 0
+		for name, db := range c.Databases {
+			ok, err := db.matches(name, term)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				selected[name] = true
+				matched++
+			}
+		}
+		if matched == 0 {
+			if strings.HasPrefix(term, tagPrefix) || strings.ContainsAny(term, "*?[") {
+				return nil, fmt.Errorf("没有匹配 %s 的数据库", term)
+			}
+			return nil, fmt.Errorf("数据库 %s 未配置", term)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("请指定数据库名称 (-d)")
+	}
+
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// matches 判断数据库是否匹配选择器中的一项
+func (dc *DatabaseConfig) matches(name, term string) (bool, error) {
+	if tag, ok := strings.CutPrefix(term, tagPrefix); ok {
+		for _, t := range dc.Tags {
+			if t == tag {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	ok, err := path.Match(term, name)
+	if err != nil {
+		return false, fmt.Errorf("无效的数据库名称模式 %s: %w", term, err)
+	}
+	return ok, nil
+}
+
+// ValidateWaves 验证分批策略
+func ValidateWaves(waves []string) error {
+	for _, spec := range waves {
+		if _, _, _, err := parseWaveSize(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseWaveSize 解析批次大小，spec 为数量（如 1）或百分比（如 10%），
+// 不是数字时 ok 为 false，表示按选择器匹配数据库
+func parseWaveSize(spec string) (n int, percent, ok bool, err error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0, false, false, fmt.Errorf("批次不能为空")
+	}
+	digits, percent := strings.CutSuffix(spec, "%")
+	n, convErr := strconv.Atoi(digits)
+	if convErr != nil {
+		if percent {
+			return 0, false, false, fmt.Errorf("无效的批次大小: %s", spec)
+		}
+		if _, err := path.Match(spec, ""); err != nil {
+			return 0, false, false, fmt.Errorf("无效的数据库名称模式 %s: %w", spec, err)
+		}
+		return 0, false, false, nil
+	}
+	if n <= 0 || (percent && n > 100) {
+		return 0, false, false, fmt.Errorf("无效的批次大小: %s", spec)
+	}
+	return n, percent, true, nil
+}
+
+// PlanWaves 按分批策略将数据库分为依次执行的批次
+//
+// 每批依次从尚未分配的数据库中选取：数量和百分比按名称顺序选取，百分比相对于全部
+// 数据库向上取整；选择器（名称、通配符模式或 tag:标签）选取匹配的数据库。剩余的
+// 数据库作为最后一批，未配置分批策略时所有数据库为一批。
+func (c *Config) PlanWaves(names []string) ([][]string, error) {
+	var waves [][]string
+	rest := names
+	for _, spec := range c.Rollout.Waves {
+		if len(rest) == 0 {
+			break
+		}
+		n, percent, ok, err := parseWaveSize(spec)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			wave, remaining, err := c.selectWave(rest, strings.TrimSpace(spec))
+			if err != nil {
+				return nil, err
+			}
+			waves = append(waves, wave)
+			rest = remaining
+			continue
+		}
+		if percent {
+			n = (len(names)*n + 99) / 100
+		}
+		n = min(n, len(rest))
+		waves = append(waves, rest[:n])
+		rest = rest[n:]
+	}
+	if len(rest) > 0 {
+		waves = append(waves, rest)
+	}
+	return waves, nil
+}
+
+// selectWave 从 names 中选出匹配选择器的数据库，返回选中和剩余的数据库
+func (c *Config) selectWave(names []string, term string) (wave, rest []string, err error) {
+	for _, name := range names {
+		db := c.Databases[name]
+		ok, err := db.matches(name, term)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			wave = append(wave, name)
+		} else {
+			rest = append(rest, name)
+		}
+	}
+	if len(wave) == 0 {
+		return nil, nil, fmt.Errorf("批次 %s 没有匹配的数据库", term)
+	}
+	return wave, rest, nil
+}
+
+// ValidateMode 验证执行模式
+func ValidateMode(mode string) error {
+	switch mode {
+	case ModeSerial, ModeParallel, ModeDAG:
+		return nil
+	}
+	return fmt.Errorf("无效的执行模式: %s，可选值为 %s、%s、%s", mode, ModeSerial, ModeParallel, ModeDAG)
+}
+
+// ValidateTx 验证事务模式
+func ValidateTx(tx string) error {
+	switch tx {
+	case TxAuto, TxFile, TxStatement:
+		return nil
+	}
+	return fmt.Errorf("无效的事务模式: %s，可选值为 %s、%s、%s", tx, TxAuto, TxFile, TxStatement)
+}
+
+// ValidateFormat 验证查询结果的输出格式
+func ValidateFormat(format string) error {
+	for _, f := range formatter.Formats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("无效的输出格式: %s，可选值为 %s", format, strings.Join(formatter.Formats, "、"))
+}
+
 // Save 保存配置到文件
 func Save(file string, cfg *Config) error {
 	data, err := json.MarshalIndent(cfg, "", "  ")
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -86,7 +88,7 @@ func TestLoad(t *testing.T) {
 				if len(cfg.Databases) != 1 {
 					t.Error("应该有一个数据库配置")
 				}
-				if cfg.MaxRetries != 3 {
+				if cfg.Retries() != 3 {
 					t.Error("MaxRetries 应该是 3")
 				}
 			},
@@ -106,7 +108,7 @@ func TestLoad(t *testing.T) {
 			}`,
 			wantErr: false,
 			validate: func(t *testing.T, cfg *Config) {
-				if cfg.MaxRetries != 3 {
+				if cfg.Retries() != 3 {
 					t.Error("MaxRetries 默认值应该是 3")
 				}
 				if cfg.MaxConcurrent != 5 {
@@ -118,8 +120,231 @@ func TestLoad(t *testing.T) {
 				if cfg.Timeout != 30 {
 					t.Error("Timeout 默认值应该是 30")
 				}
+				if cfg.Mode != ModeSerial {
+					t.Error("Mode 默认值应该是 serial")
+				}
+				if cfg.Tx != TxAuto {
+					t.Error("Tx 默认值应该是 auto")
+				}
+				if cfg.LOBLimit != 65536 || cfg.MaxRows != 0 {
+					t.Errorf("LOBLimit 默认值应该是 65536，MaxRows 默认值应该是 0，实际为 %d、%d", cfg.LOBLimit, cfg.MaxRows)
+				}
+				if cfg.Format != "table" {
+					t.Error("Format 默认值应该是 table")
+				}
+				if cfg.DBConcurrency != 1 {
+					t.Error("DBConcurrency 默认值应该是 1")
+				}
+				if cfg.Retry.BaseDelay != 100 || cfg.Retry.MaxDelay != 5000 || cfg.Retry.MaxElapsed != 60 {
+					t.Errorf("Retry 默认值不正确: %+v", cfg.Retry)
+				}
+			},
+		},
+		{
+			name: "重试配置",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"retry": {"base_delay_ms": 200, "codes": [60, 8177]}
+			}`,
+			wantErr: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Retry.BaseDelay != 200 || cfg.Retry.MaxDelay != 5000 {
+					t.Errorf("Retry 配置不正确: %+v", cfg.Retry)
+				}
+				if len(cfg.Retry.Codes) != 2 || cfg.Retry.Codes[1] != 8177 {
+					t.Errorf("Retry.Codes 应该是 [60 8177]，实际为 %v", cfg.Retry.Codes)
+				}
+			},
+		},
+		{
+			name: "无效重试配置",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"retry": {"base_delay_ms": 1000, "max_delay_ms": 500}
+			}`,
+			wantErr:  true,
+			validate: nil,
+		},
+		{
+			name: "无效执行模式",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"mode": "random"
+			}`,
+			wantErr:  true,
+			validate: nil,
+		},
+		{
+			name: "无效事务模式",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"tx": "manual"
+			}`,
+			wantErr:  true,
+			validate: nil,
+		},
+		{
+			name: "显示格式",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"display": {"timestamp_format": "2006-01-02 15:04:05.000", "null": ""}
+			}`,
+			wantErr: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Display.TimestampFormat != "2006-01-02 15:04:05.000" || cfg.Display.DateFormat != "" {
+					t.Errorf("Display 配置不正确: %+v", cfg.Display)
+				}
+				if cfg.Display.Null == nil || *cfg.Display.Null != "" {
+					t.Error("Display.Null 应该是空字符串")
+				}
 			},
 		},
+		{
+			name: "无效最大行数",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"max_rows": -1
+			}`,
+			wantErr:  true,
+			validate: nil,
+		},
+		{
+			name: "无效输出格式",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"format": "xml"
+			}`,
+			wantErr:  true,
+			validate: nil,
+		},
+		{
+			name: "关闭重试",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"max_retries": 0
+			}`,
+			wantErr: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Retries() != 0 {
+					t.Errorf("max_retries 为 0 时不应重试，实际为 %d", cfg.Retries())
+				}
+			},
+		},
+		{
+			name: "无效重试次数",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"max_retries": -1
+			}`,
+			wantErr:  true,
+			validate: nil,
+		},
+		{
+			name: "无效数据库并发数",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"db_concurrency": -1
+			}`,
+			wantErr:  true,
+			validate: nil,
+		},
+		{
+			name: "无效分批策略",
+			content: `{
+				"databases": {
+					"test": {
+						"user": "test_user",
+						"password": "test_pass",
+						"host": "localhost",
+						"port": 1521,
+						"service": "ORCL"
+					}
+				},
+				"rollout": {"waves": ["1", "150%"]}
+			}`,
+			wantErr:  true,
+			validate: nil,
+		},
 		{
 			name:     "无效JSON",
 			content:  `{invalid json`,
@@ -191,7 +416,7 @@ func TestLoad(t *testing.T) {
 				if db.IdleTimeout != 300 {
 					t.Error("IdleTimeout 应该是 300")
 				}
-				if cfg.MaxRetries != 5 {
+				if cfg.Retries() != 5 {
 					t.Error("MaxRetries 应该是 5")
 				}
 				if cfg.LogLevel != "debug" {
@@ -326,3 +551,101 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectDatabases(t *testing.T) {
+	cfg := &Config{
+		Databases: map[string]DatabaseConfig{
+			"prod-east": {Tags: []string{"prod", "east"}},
+			"prod-west": {Tags: []string{"prod"}},
+			"test":      {Tags: []string{"test"}},
+			"dev":       {},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		selector string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "单个名称", selector: "dev", want: []string{"dev"}},
+		{name: "名称列表", selector: "test, dev", want: []string{"dev", "test"}},
+		{name: "通配符", selector: "prod-*", want: []string{"prod-east", "prod-west"}},
+		{name: "标签", selector: "tag:east", want: []string{"prod-east"}},
+		{name: "并集去重", selector: "tag:prod,prod-west,test", want: []string{"prod-east", "prod-west", "test"}},
+		{name: "名称不存在", selector: "dev,missing", wantErr: true},
+		{name: "通配符无匹配", selector: "qa-*", wantErr: true},
+		{name: "标签无匹配", selector: "tag:qa", wantErr: true},
+		{name: "无效模式", selector: "prod-[", wantErr: true},
+		{name: "空选择器", selector: " , ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cfg.SelectDatabases(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelectDatabases() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("SelectDatabases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanWaves(t *testing.T) {
+	databases := map[string]DatabaseConfig{"canary": {Tags: []string{"canary"}}}
+	var names []string
+	for i := 1; i <= 20; i++ {
+		name := fmt.Sprintf("db%02d", i)
+		databases[name] = DatabaseConfig{}
+		names = append(names, name)
+	}
+	all := append([]string{"canary"}, names...)
+
+	tests := []struct {
+		name    string
+		waves   []string
+		want    []int // 每批的数据库数
+		first   []string
+		wantErr bool
+	}{
+		{name: "未分批", want: []int{21}},
+		{name: "数量和百分比", waves: []string{"1", "10%"}, want: []int{1, 3, 17}, first: []string{"canary"}},
+		{name: "覆盖全部", waves: []string{"2", "100%"}, want: []int{2, 19}},
+		{name: "批次多于数据库", waves: []string{"20", "5", "5"}, want: []int{20, 1}},
+		{name: "按标签选择", waves: []string{"tag:canary", "50%"}, want: []int{1, 11, 9}, first: []string{"canary"}},
+		{name: "按通配符选择", waves: []string{"db1*", "db2*"}, want: []int{10, 1, 10}},
+		{name: "选择器无匹配", waves: []string{"tag:qa"}, wantErr: true},
+		{name: "无效百分比", waves: []string{"0%"}, wantErr: true},
+		{name: "无效数量", waves: []string{"-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Databases: databases, Rollout: RolloutConfig{Waves: tt.waves}}
+			waves, err := cfg.PlanWaves(all)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PlanWaves() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var sizes []int
+			total := 0
+			for _, wave := range waves {
+				sizes = append(sizes, len(wave))
+				total += len(wave)
+			}
+			if fmt.Sprint(sizes) != fmt.Sprint(tt.want) {
+				t.Errorf("PlanWaves() 批次大小 = %v, want %v", sizes, tt.want)
+			}
+			if total != len(all) {
+				t.Errorf("PlanWaves() 共 %d 个数据库, want %d", total, len(all))
+			}
+			if tt.first != nil && strings.Join(waves[0], ",") != strings.Join(tt.first, ",") {
+				t.Errorf("PlanWaves() 第一批 = %v, want %v", waves[0], tt.first)
+			}
+		})
+	}
+}
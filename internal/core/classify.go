@@ -0,0 +1,299 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// classification 语句分类结果
+type classification struct {
+	sqlType models.SQLType
+	verb    string // 语句动作，如 SELECT、CREATE TABLE
+	object  string // 目标对象，如 APP.USERS
+}
+
+// plsqlUnits 以 / 结束、内部可包含分号的 PL/SQL 程序单元
+var plsqlUnits = map[string]bool{
+	"PROCEDURE":     true,
+	"FUNCTION":      true,
+	"PACKAGE":       true,
+	"PACKAGE BODY":  true,
+	"TRIGGER":       true,
+	"TYPE":          true,
+	"TYPE BODY":     true,
+	"LIBRARY":       true,
+	"JAVA SOURCE":   true,
+	"JAVA CLASS":    true,
+	"JAVA RESOURCE": true,
+}
+
+// objectModifiers DDL 中对象类型之前可能出现的修饰词
+var objectModifiers = map[string]bool{
+	"OR": true, "REPLACE": true, "AND": true, "RESOLVE": true, "COMPILE": true, "NOCOMPILE": true,
+	"EDITIONABLE": true, "NONEDITIONABLE": true, "EDITIONING": true, "FORCE": true, "NOFORCE": true,
+	"GLOBAL": true, "PRIVATE": true, "TEMPORARY": true, "SHARDED": true, "DUPLICATED": true,
+	"BLOCKCHAIN": true, "IMMUTABLE": true, "UNIQUE": true, "BITMAP": true, "MULTIVALUE": true,
+	"PUBLIC": true, "SHARED": true, "BIGFILE": true, "SMALLFILE": true, "UNDO": true,
+}
+
+// compoundTypes 由多个单词组成的对象类型
+var compoundTypes = map[string][]string{
+	"PACKAGE":      {"BODY"},
+	"TYPE":         {"BODY"},
+	"JAVA":         {"SOURCE", "CLASS", "RESOURCE"},
+	"MATERIALIZED": {"VIEW", "ZONEMAP"},
+	"DATABASE":     {"LINK"},
+	"PLUGGABLE":    {"DATABASE"},
+}
+
+// unnamedTypes 不带对象名的 ALTER 目标
+var unnamedTypes = map[string]bool{
+	"SESSION":  true,
+	"SYSTEM":   true,
+	"DATABASE": true,
+}
+
+// tokenCursor 在有效词法单元上移动的游标
+type tokenCursor struct {
+	tokens []token
+	pos    int
+}
+
+// peek 返回当前词法单元，越界时返回 EOF
+func (c *tokenCursor) peek() token {
+	if c.pos >= len(c.tokens) {
+		return token{kind: tokenEOF}
+	}
+	return c.tokens[c.pos]
+}
+
+// word 返回当前单词的大写形式，不是单词时返回空字符串
+func (c *tokenCursor) word() string {
+	if tok := c.peek(); tok.kind == tokenWord {
+		return tok.upper()
+	}
+	return ""
+}
+
+// symbol 判断当前词法单元是否为指定符号
+func (c *tokenCursor) symbol(text string) bool {
+	tok := c.peek()
+	return tok.kind == tokenSymbol && tok.text == text
+}
+
+func (c *tokenCursor) advance() {
+	c.pos++
+}
+
+// accept 当前单词属于 words 时前进并返回 true
+func (c *tokenCursor) accept(words ...string) bool {
+	word := c.word()
+	for _, w := range words {
+		if word == w {
+			c.advance()
+			return true
+		}
+	}
+	return false
+}
+
+// namePart 读取一段标识符，普通标识符转为大写，引号标识符保留原样
+func (c *tokenCursor) namePart() (string, bool) {
+	tok := c.peek()
+	switch tok.kind {
+	case tokenWord:
+		c.advance()
+		return tok.upper(), true
+	case tokenQuotedIdent:
+		c.advance()
+		return strings.ReplaceAll(tok.text[1:len(tok.text)-1], `""`, `"`), true
+	}
+	return "", false
+}
+
+// name 读取可能带模式名的对象名，如 app.users
+func (c *tokenCursor) name() string {
+	part, ok := c.namePart()
+	if !ok {
+		return ""
+	}
+	parts := []string{part}
+	for c.symbol(".") {
+		c.advance()
+		part, ok := c.namePart()
+		if !ok {
+			break
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// skipTo 前进到指定单词之后，找不到时返回 false
+func (c *tokenCursor) skipTo(word string) bool {
+	for c.pos < len(c.tokens) {
+		if c.accept(word) {
+			return true
+		}
+		c.advance()
+	}
+	return false
+}
+
+// objectType 读取修饰词之后的对象类型，如 TABLE、PACKAGE BODY
+func (c *tokenCursor) objectType() string {
+	for objectModifiers[c.word()] {
+		c.advance()
+	}
+	first := c.word()
+	if first == "" {
+		return ""
+	}
+	c.advance()
+	if c.accept(compoundTypes[first]...) {
+		first += " " + c.tokens[c.pos-1].upper()
+	}
+	if first == "MATERIALIZED VIEW" && c.accept("LOG") {
+		first += " LOG"
+	}
+	return first
+}
+
+// classify 对替换变量后的语句文本进行分类
+func classify(text string) classification {
+	return classifyTokens(significantTokens(text))
+}
+
+// significantTokens 返回文本中除空白和注释以外的词法单元
+func significantTokens(text string) []token {
+	lex := newLexer(strings.NewReader(text))
+	var tokens []token
+	for {
+		tok, err := lex.next()
+		if err != nil || tok.kind == tokenEOF {
+			break
+		}
+		if tok.significant() {
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens
+}
+
+// classifyTokens 根据语句的有效词法单元判断类型、动作和目标对象
+func classifyTokens(tokens []token) classification {
+	c := &tokenCursor{tokens: tokens}
+
+	// (SELECT ...) 以及 <<label>> 开头的块
+	if c.symbol("(") {
+		for c.symbol("(") {
+			c.advance()
+		}
+		if c.accept("SELECT", "WITH") {
+			return classification{sqlType: models.SQLTypeQuery, verb: "SELECT"}
+		}
+		return classification{sqlType: models.SQLTypeExec}
+	}
+	if c.symbol("<") {
+		return classification{sqlType: models.SQLTypeBlock, verb: "BEGIN"}
+	}
+
+	verb := c.word()
+	c.advance()
+	switch verb {
+	case "SELECT", "WITH":
+		return classification{sqlType: models.SQLTypeQuery, verb: "SELECT"}
+	case "INSERT":
+		// INSERT ALL/FIRST 取第一个 INTO 的目标表
+		c.skipTo("INTO")
+		return classification{sqlType: models.SQLTypeDML, verb: verb, object: c.name()}
+	case "UPDATE":
+		return classification{sqlType: models.SQLTypeDML, verb: verb, object: c.name()}
+	case "DELETE":
+		c.accept("FROM")
+		return classification{sqlType: models.SQLTypeDML, verb: verb, object: c.name()}
+	case "MERGE":
+		c.accept("INTO")
+		return classification{sqlType: models.SQLTypeDML, verb: verb, object: c.name()}
+	case "CALL":
+		return classification{sqlType: models.SQLTypeDML, verb: verb, object: c.name()}
+	case "LOCK":
+		c.accept("TABLE")
+		return classification{sqlType: models.SQLTypeDML, verb: "LOCK TABLE", object: c.name()}
+	case "EXPLAIN":
+		return classification{sqlType: models.SQLTypeDML, verb: "EXPLAIN PLAN"}
+	case "COMMIT", "ROLLBACK", "SAVEPOINT":
+		return classification{sqlType: models.SQLTypeTCL, verb: verb}
+	case "SET":
+		switch c.word() {
+		case "TRANSACTION":
+			return classification{sqlType: models.SQLTypeTCL, verb: "SET TRANSACTION"}
+		case "CONSTRAINT", "CONSTRAINTS":
+			return classification{sqlType: models.SQLTypeTCL, verb: "SET CONSTRAINTS"}
+		}
+		return classification{sqlType: models.SQLTypeExec, verb: strings.TrimSpace("SET " + c.word())}
+	case "GRANT", "REVOKE":
+		return classification{sqlType: models.SQLTypeDCL, verb: verb, object: grantObject(c)}
+	case "CREATE", "ALTER", "DROP", "TRUNCATE", "ANALYZE", "PURGE", "FLASHBACK":
+		return classifyDDL(c, verb)
+	case "RENAME":
+		return classification{sqlType: models.SQLTypeDDL, verb: verb, object: c.name()}
+	case "COMMENT":
+		c.accept("ON")
+		objType := c.objectType()
+		object := c.name()
+		if objType == "COLUMN" {
+			// 列注释的目标对象为所在的表
+			if i := strings.LastIndex(object, "."); i >= 0 {
+				object = object[:i]
+			}
+		}
+		return classification{sqlType: models.SQLTypeDDL, verb: verb, object: object}
+	case "AUDIT", "NOAUDIT", "ASSOCIATE", "DISASSOCIATE":
+		return classification{sqlType: models.SQLTypeDDL, verb: verb}
+	case "BEGIN", "DECLARE":
+		return classification{sqlType: models.SQLTypeBlock, verb: verb}
+	}
+	return classification{sqlType: models.SQLTypeExec, verb: verb}
+}
+
+// classifyDDL 解析 CREATE/ALTER/DROP 等语句的对象类型和名称
+func classifyDDL(c *tokenCursor, verb string) classification {
+	objType := c.objectType()
+	if objType == "" {
+		return classification{sqlType: models.SQLTypeDDL, verb: verb}
+	}
+
+	result := classification{sqlType: models.SQLTypeDDL, verb: verb + " " + objType}
+	switch {
+	case verb == "CREATE" && plsqlUnits[objType]:
+		result.sqlType = models.SQLTypePLSQL
+	case verb == "ALTER" && (objType == "SESSION" || objType == "SYSTEM"):
+		result.sqlType = models.SQLTypeExec
+	}
+	if unnamedTypes[objType] {
+		return result
+	}
+
+	if c.accept("IF") {
+		c.accept("NOT")
+		c.accept("EXISTS")
+	}
+	c.accept("NAMED", "ON")
+	result.object = c.name()
+	return result
+}
+
+// grantObject 返回 GRANT/REVOKE 中 ON 之后的对象名，系统权限没有对象
+func grantObject(c *tokenCursor) string {
+	if !c.skipTo("ON") {
+		return ""
+	}
+	object := c.name()
+	// ON DIRECTORY d、ON JAVA SOURCE j 等形式，前面读取的是对象类型
+	for word := c.word(); word != "" && word != "TO" && word != "FROM"; word = c.word() {
+		object = c.name()
+	}
+	return object
+}
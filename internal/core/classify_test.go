@@ -0,0 +1,121 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		sql    string
+		typ    models.SQLType
+		verb   string
+		object string
+	}{
+		// 查询
+		{"SELECT * FROM users", models.SQLTypeQuery, "SELECT", ""},
+		{"WITH t AS (SELECT 1 FROM dual) SELECT * FROM t", models.SQLTypeQuery, "SELECT", ""},
+		{"((SELECT 1 FROM dual) UNION (SELECT 2 FROM dual))", models.SQLTypeQuery, "SELECT", ""},
+		{"/* 提示 */ select 1 from dual", models.SQLTypeQuery, "SELECT", ""},
+
+		// DML
+		{"INSERT INTO app.users (id) VALUES (1)", models.SQLTypeDML, "INSERT", "APP.USERS"},
+		{"INSERT /*+ APPEND */ ALL INTO t1 VALUES (1) INTO t2 VALUES (2) SELECT * FROM dual", models.SQLTypeDML, "INSERT", "T1"},
+		{`UPDATE "App"."Users" SET a = 1`, models.SQLTypeDML, "UPDATE", "App.Users"},
+		{"DELETE users WHERE id = 1", models.SQLTypeDML, "DELETE", "USERS"},
+		{"DELETE FROM users", models.SQLTypeDML, "DELETE", "USERS"},
+		{"MERGE INTO t USING s ON (t.id = s.id) WHEN MATCHED THEN UPDATE SET t.a = s.a", models.SQLTypeDML, "MERGE", "T"},
+		{"CALL app.pkg.proc(1)", models.SQLTypeDML, "CALL", "APP.PKG.PROC"},
+		{"EXPLAIN PLAN FOR SELECT * FROM t", models.SQLTypeDML, "EXPLAIN PLAN", ""},
+		{"LOCK TABLE t IN EXCLUSIVE MODE", models.SQLTypeDML, "LOCK TABLE", "T"},
+
+		// DDL
+		{"CREATE TABLE t (id NUMBER)", models.SQLTypeDDL, "CREATE TABLE", "T"},
+		{"CREATE GLOBAL TEMPORARY TABLE tmp (id NUMBER)", models.SQLTypeDDL, "CREATE TABLE", "TMP"},
+		{"CREATE UNIQUE INDEX app.idx ON t (id)", models.SQLTypeDDL, "CREATE INDEX", "APP.IDX"},
+		{"CREATE OR REPLACE FORCE VIEW v AS SELECT 1 FROM dual", models.SQLTypeDDL, "CREATE VIEW", "V"},
+		{"CREATE MATERIALIZED VIEW LOG ON app.t", models.SQLTypeDDL, "CREATE MATERIALIZED VIEW LOG", "APP.T"},
+		{"CREATE PUBLIC DATABASE LINK remote CONNECT TO u IDENTIFIED BY p", models.SQLTypeDDL, "CREATE DATABASE LINK", "REMOTE"},
+		{"CREATE TABLE IF NOT EXISTS t (id NUMBER)", models.SQLTypeDDL, "CREATE TABLE", "T"},
+		{"ALTER TABLE t ADD (c NUMBER)", models.SQLTypeDDL, "ALTER TABLE", "T"},
+		{"ALTER PACKAGE p COMPILE BODY", models.SQLTypeDDL, "ALTER PACKAGE", "P"},
+		{"DROP PACKAGE BODY app.p", models.SQLTypeDDL, "DROP PACKAGE BODY", "APP.P"},
+		{"TRUNCATE TABLE t", models.SQLTypeDDL, "TRUNCATE TABLE", "T"},
+		{"RENAME a TO b", models.SQLTypeDDL, "RENAME", "A"},
+		{"COMMENT ON COLUMN app.t.c IS 'x'", models.SQLTypeDDL, "COMMENT", "APP.T"},
+		{"COMMENT ON TABLE t IS 'x'", models.SQLTypeDDL, "COMMENT", "T"},
+
+		// DCL 与 TCL
+		{"GRANT SELECT, INSERT ON app.t TO r", models.SQLTypeDCL, "GRANT", "APP.T"},
+		{"GRANT READ ON DIRECTORY data_dir TO r", models.SQLTypeDCL, "GRANT", "DATA_DIR"},
+		{"GRANT CREATE SESSION TO u", models.SQLTypeDCL, "GRANT", ""},
+		{"REVOKE SELECT ON t FROM r", models.SQLTypeDCL, "REVOKE", "T"},
+		{"COMMIT", models.SQLTypeTCL, "COMMIT", ""},
+		{"ROLLBACK TO SAVEPOINT sp", models.SQLTypeTCL, "ROLLBACK", ""},
+		{"SAVEPOINT sp", models.SQLTypeTCL, "SAVEPOINT", ""},
+		{"SET TRANSACTION READ ONLY", models.SQLTypeTCL, "SET TRANSACTION", ""},
+
+		// PL/SQL
+		{"CREATE OR REPLACE EDITIONABLE PACKAGE BODY app.p AS END;", models.SQLTypePLSQL, "CREATE PACKAGE BODY", "APP.P"},
+		{"CREATE TYPE t_obj AS OBJECT (id NUMBER);", models.SQLTypePLSQL, "CREATE TYPE", "T_OBJ"},
+		{"CREATE OR REPLACE TYPE BODY t_obj AS END;", models.SQLTypePLSQL, "CREATE TYPE BODY", "T_OBJ"},
+		{"CREATE LIBRARY ext_lib AS '/lib/ext.so';", models.SQLTypePLSQL, "CREATE LIBRARY", "EXT_LIB"},
+		{`CREATE OR REPLACE AND COMPILE JAVA SOURCE NAMED "Hello" AS public class Hello {}`, models.SQLTypePLSQL, "CREATE JAVA SOURCE", "Hello"},
+		{"BEGIN NULL; END;", models.SQLTypeBlock, "BEGIN", ""},
+		{"DECLARE x NUMBER; BEGIN NULL; END;", models.SQLTypeBlock, "DECLARE", ""},
+		{"<<outer>> BEGIN NULL; END;", models.SQLTypeBlock, "BEGIN", ""},
+
+		// 其他
+		{"ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD'", models.SQLTypeExec, "ALTER SESSION", ""},
+		{"ALTER SYSTEM FLUSH SHARED_POOL", models.SQLTypeExec, "ALTER SYSTEM", ""},
+		{"SET ROLE ALL", models.SQLTypeExec, "SET ROLE", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			got := classify(tt.sql)
+			assert.Equal(t, tt.typ, got.sqlType)
+			assert.Equal(t, tt.verb, got.verb)
+			assert.Equal(t, tt.object, got.object)
+		})
+	}
+}
+
+func TestParsePLSQLUnits(t *testing.T) {
+	content := `CREATE OR REPLACE TYPE t_obj AS OBJECT (
+  id   NUMBER,
+  name VARCHAR2(30)
+);
+/
+CREATE OR REPLACE TYPE BODY t_obj AS
+  MEMBER FUNCTION f RETURN NUMBER IS BEGIN RETURN 1; END;
+END;
+/
+CREATE LIBRARY ext_lib AS '/lib/ext.so';
+/
+WITH t AS (SELECT 1 AS x FROM dual)
+SELECT x FROM t;
+`
+	parser := ParseReader(strings.NewReader(content), ParseOptions{})
+	defer parser.Close()
+
+	var tasks []models.SQLTask
+	for parser.Next() {
+		tasks = append(tasks, parser.Task())
+	}
+	require.NoError(t, parser.Err())
+	require.Len(t, tasks, 4)
+
+	assert.Equal(t, "CREATE TYPE", tasks[0].Verb)
+	assert.Equal(t, 5, tasks[0].LineNum)
+	assert.Equal(t, "CREATE TYPE BODY", tasks[1].Verb)
+	assert.Equal(t, "CREATE LIBRARY", tasks[2].Verb)
+	for _, task := range tasks[:3] {
+		assert.Equal(t, models.SQLTypePLSQL, task.Type)
+	}
+	assert.Equal(t, models.SQLTypeQuery, tasks[3].Type)
+}
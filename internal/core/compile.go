@@ -0,0 +1,173 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// compiledUnits 创建后需要检查编译错误的 PL/SQL 程序单元，名称与数据字典中的类型一致
+var compiledUnits = map[string]bool{
+	"PROCEDURE":    true,
+	"FUNCTION":     true,
+	"PACKAGE":      true,
+	"PACKAGE BODY": true,
+	"TRIGGER":      true,
+	"TYPE":         true,
+	"TYPE BODY":    true,
+}
+
+// compileErrorCode Oracle 对带编译错误的程序单元返回的警告码 ORA-24344，
+// 可通过 -- @runner:ignore ORA-24344 忽略编译错误
+const compileErrorCode = 24344
+
+// 未指定模式名的程序单元创建在会话的当前模式中，ALTER SESSION SET CURRENT_SCHEMA
+// 之后与登录用户不同，因此不能查询 USER_ERRORS
+const (
+	currentSchemaErrorsQuery = `SELECT line, position, text, attribute FROM all_errors
+WHERE owner = SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') AND name = :1 AND type = :2 ORDER BY sequence`
+	allErrorsQuery = `SELECT line, position, text, attribute FROM all_errors
+WHERE owner = :1 AND name = :2 AND type = :3 ORDER BY sequence`
+)
+
+// compileError 数据字典中记录的一条编译错误或警告
+type compileError struct {
+	line     int // 相对于程序单元源码的行号
+	position int
+	text     string
+	warning  bool
+}
+
+// compiledUnit 返回任务创建的程序单元的模式名、名称和类型，不需要检查时 ok 为 false
+func compiledUnit(task models.SQLTask) (owner, name, unitType string, ok bool) {
+	unitType = strings.TrimPrefix(task.Verb, "CREATE ")
+	if task.Type != models.SQLTypePLSQL || !compiledUnits[unitType] || task.Object == "" {
+		return "", "", "", false
+	}
+	name = task.Object
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		owner, name = name[:i], name[i+1:]
+	}
+	return owner, name, unitType, true
+}
+
+// compileErrors 查询程序单元的编译错误和警告，未指定模式名时查询会话当前模式中的对象
+func compileErrors(ctx context.Context, q querier, task models.SQLTask) ([]compileError, error) {
+	owner, name, unitType, ok := compiledUnit(task)
+	if !ok {
+		return nil, nil
+	}
+
+	query, args := currentSchemaErrorsQuery, []interface{}{name, unitType}
+	if owner != "" {
+		query, args = allErrorsQuery, []interface{}{owner, name, unitType}
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询编译错误失败: %w", err)
+	}
+	defer rows.Close()
+
+	var errs []compileError
+	for rows.Next() {
+		var ce compileError
+		var attribute string
+		if err := rows.Scan(&ce.line, &ce.position, &ce.text, &attribute); err != nil {
+			return nil, fmt.Errorf("读取编译错误失败: %w", err)
+		}
+		ce.text = strings.TrimSpace(ce.text)
+		ce.warning = attribute == "WARNING"
+		errs = append(errs, ce)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取编译错误失败: %w", err)
+	}
+	return errs, nil
+}
+
+// unitSourceStart 返回程序单元源码第一行在执行文本中的行列号
+//
+// 数据字典中的源码从对象类型关键字开始；触发器的错误行号从触发器体的
+// DECLARE、BEGIN 或 COMPOUND TRIGGER 开始计算。
+func unitSourceStart(task models.SQLTask) (int, int) {
+	c := &tokenCursor{tokens: significantTokens(task.SQL)}
+	c.accept("CREATE")
+	for objectModifiers[c.word()] {
+		c.advance()
+	}
+	if c.word() == "TRIGGER" {
+		for c.pos < len(c.tokens) && c.word() != "DECLARE" && c.word() != "BEGIN" && c.word() != "COMPOUND" {
+			c.advance()
+		}
+	}
+	tok := c.peek()
+	if tok.kind == tokenEOF {
+		return 1, 1
+	}
+	return tok.line, tok.col
+}
+
+// sourcePosition 将编译错误的位置映射回源文件
+func (ce compileError) sourcePosition(task models.SQLTask) *models.SourcePosition {
+	if ce.line < 1 {
+		return nil
+	}
+	startLine, startCol := unitSourceStart(task)
+	col := ce.position
+	if ce.line == 1 {
+		col += startCol - 1
+	}
+	pos, ok := sourcePosition(task, startLine+ce.line-1, col)
+	if !ok {
+		return nil
+	}
+	return &pos
+}
+
+func (ce compileError) String() string {
+	return fmt.Sprintf("第 %d 行第 %d 列: %s", ce.line, ce.position, ce.text)
+}
+
+// message 返回带程序单元名称的描述
+func (ce compileError) message(task models.SQLTask) string {
+	return fmt.Sprintf("%s %s %s", task.Verb, task.Object, ce)
+}
+
+// checkCompileErrors 检查程序单元的编译结果，存在编译错误时返回错误，编译警告写入输出后返回
+func (e *Executor) checkCompileErrors(ctx context.Context, q querier, task models.SQLTask, output *outputCapture) ([]compileError, error) {
+	errs, err := compileErrors(ctx, q, task)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings, failures []compileError
+	for _, ce := range errs {
+		if ce.warning {
+			warnings = append(warnings, ce)
+		} else {
+			failures = append(failures, ce)
+		}
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(output, "编译警告: %s\n", w.message(task))
+	}
+	if len(failures) == 0 {
+		return warnings, nil
+	}
+
+	if task.Directives.Ignores(compileErrorCode) {
+		fmt.Fprintf(output, "已忽略错误 ORA-%05d\n", compileErrorCode)
+		return warnings, nil
+	}
+	lines := make([]string, len(failures))
+	for i, ce := range failures {
+		lines[i] = ce.String()
+	}
+	err = fmt.Errorf("ORA-%05d: %s %s 编译失败:\n%s", compileErrorCode, task.Verb, task.Object, strings.Join(lines, "\n"))
+	if pos := failures[0].sourcePosition(task); pos != nil {
+		err = &models.PositionError{Err: err, Position: *pos}
+	}
+	return warnings, err
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompiledUnit(t *testing.T) {
+	tests := []struct {
+		sql      string
+		owner    string
+		name     string
+		unitType string
+		ok       bool
+	}{
+		{sql: "CREATE OR REPLACE PROCEDURE p AS BEGIN NULL; END;\n/", name: "P", unitType: "PROCEDURE", ok: true},
+		{sql: "CREATE EDITIONABLE PACKAGE BODY app.pkg AS END;\n/", owner: "APP", name: "PKG", unitType: "PACKAGE BODY", ok: true},
+		{sql: `CREATE TRIGGER "Trg" BEFORE INSERT ON t BEGIN NULL; END;` + "\n/", name: "Trg", unitType: "TRIGGER", ok: true},
+		{sql: "CREATE OR REPLACE TYPE t_obj AS OBJECT (id NUMBER);\n/", name: "T_OBJ", unitType: "TYPE", ok: true},
+		{sql: "CREATE TABLE t (id NUMBER);"},
+		{sql: "BEGIN NULL; END;\n/"},
+		{sql: "CREATE OR REPLACE AND COMPILE JAVA SOURCE NAMED Hello AS public class Hello {}\n/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			owner, name, unitType, ok := compiledUnit(parseOne(t, tt.sql))
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.owner, owner)
+			assert.Equal(t, tt.name, name)
+			assert.Equal(t, tt.unitType, unitType)
+		})
+	}
+}
+
+func TestCompileErrorsQuery(t *testing.T) {
+	t.Run("当前模式", func(t *testing.T) {
+		// ALTER SESSION SET CURRENT_SCHEMA 之后未指定模式名的对象属于当前模式，而不是登录用户
+		q := &stubQuerier{}
+		_, err := compileErrors(context.Background(), q, parseOne(t, "CREATE OR REPLACE PROCEDURE p AS BEGIN NULL; END;\n/"))
+		require.Error(t, err)
+		require.Len(t, q.queries, 1)
+		assert.Contains(t, q.queries[0], "all_errors")
+		assert.Contains(t, q.queries[0], "SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA')")
+		assert.Equal(t, []interface{}{"P", "PROCEDURE"}, q.args[0])
+	})
+
+	t.Run("指定模式", func(t *testing.T) {
+		q := &stubQuerier{}
+		_, err := compileErrors(context.Background(), q, parseOne(t, "CREATE OR REPLACE PACKAGE app.pkg AS END;\n/"))
+		require.Error(t, err)
+		require.Len(t, q.queries, 1)
+		assert.NotContains(t, q.queries[0], "CURRENT_SCHEMA")
+		assert.Equal(t, []interface{}{"APP", "PKG", "PACKAGE"}, q.args[0])
+	})
+}
+
+func TestCompileErrorPosition(t *testing.T) {
+	t.Run("存储过程", func(t *testing.T) {
+		task := parseOne(t, "-- 部署\nCREATE OR REPLACE PROCEDURE p AS\nBEGIN\n\n  x := 1;\nEND;\n/\n")
+		// 数据字典中的源码从 PROCEDURE 开始，执行前已去除空行并将缩进调整为 4 个空格
+		pos := compileError{line: 3, position: 5, text: "PLS-00201"}.sourcePosition(task)
+		require.NotNil(t, pos)
+		assert.Equal(t, models.SourcePosition{Line: 5, Column: 3, Source: "  x := 1;"}, *pos)
+
+		pos = compileError{line: 1, position: 11, text: "PLS-00410"}.sourcePosition(task)
+		require.NotNil(t, pos)
+		assert.Equal(t, 2, pos.Line)
+		assert.Equal(t, 29, pos.Column)
+	})
+
+	t.Run("触发器", func(t *testing.T) {
+		task := parseOne(t, "CREATE OR REPLACE TRIGGER trg\n  BEFORE INSERT ON t\n  FOR EACH ROW\nBEGIN\n  :new.id := seq.nextval\nEND;\n/\n")
+		// 触发器的行号从触发器体开始
+		pos := compileError{line: 3, position: 1, text: "PLS-00103"}.sourcePosition(task)
+		require.NotNil(t, pos)
+		assert.Equal(t, 6, pos.Line)
+	})
+
+	t.Run("超出范围", func(t *testing.T) {
+		task := parseOne(t, "CREATE PROCEDURE p AS BEGIN NULL; END;\n/\n")
+		assert.Nil(t, compileError{line: 5, position: 1}.sourcePosition(task))
+	})
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// directivePrefix 指令注释的前缀，如 -- @runner:timeout 600
+const directivePrefix = "@runner:"
+
+// oraCodeArg 匹配 ORA-00955 或 955 形式的错误码
+var oraCodeArg = regexp.MustCompile(`(?i)^(?:ORA-)?(\d+)$`)
+
+// statementName 匹配可用作文件名的语句名称
+var statementName = regexp.MustCompile(`^[\p{L}\p{N}_][\p{L}\p{N}_.-]*$`)
+
+// parseDirective 解析行注释中的指令并合并到 d，注释不是指令时返回 false
+func parseDirective(comment string, d *models.Directives) (bool, error) {
+	text := strings.TrimSpace(strings.TrimPrefix(comment, "--"))
+	if !strings.HasPrefix(text, directivePrefix) {
+		return false, nil
+	}
+
+	name, args, _ := strings.Cut(strings.TrimPrefix(text, directivePrefix), " ")
+	args = strings.TrimSpace(args)
+	values := strings.FieldsFunc(args, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	switch strings.ToLower(name) {
+	case "timeout":
+		timeout, err := parseTimeout(args)
+		if err != nil {
+			return true, err
+		}
+		d.Timeout = timeout
+	case "retries":
+		retries, err := strconv.Atoi(args)
+		if err != nil || retries < 0 {
+			return true, fmt.Errorf("无效的重试次数: %q", args)
+		}
+		d.Retries = &retries
+	case "max_rows":
+		maxRows, err := strconv.Atoi(args)
+		if err != nil || maxRows < 0 {
+			return true, fmt.Errorf("无效的最大行数: %q", args)
+		}
+		d.MaxRows = &maxRows
+	case "ignore":
+		if len(values) == 0 {
+			return true, fmt.Errorf("@runner:ignore 缺少错误码")
+		}
+		for _, value := range values {
+			match := oraCodeArg.FindStringSubmatch(value)
+			if match == nil {
+				return true, fmt.Errorf("无效的错误码: %q", value)
+			}
+			code, _ := strconv.Atoi(match[1])
+			d.Ignore = append(d.Ignore, code)
+		}
+	case "serial":
+		if args != "" {
+			return true, fmt.Errorf("@runner:serial 不接受参数: %q", args)
+		}
+		d.Serial = true
+	case "idempotent":
+		if args != "" {
+			return true, fmt.Errorf("@runner:idempotent 不接受参数: %q", args)
+		}
+		d.Idempotent = true
+	case "tag":
+		if len(values) == 0 {
+			return true, fmt.Errorf("@runner:tag 缺少标签名")
+		}
+		d.Tags = append(d.Tags, values...)
+	case "name":
+		if !statementName.MatchString(args) {
+			return true, fmt.Errorf("无效的语句名称: %q，只能包含字母、数字、下划线、点和连字符", args)
+		}
+		d.Name = args
+	case "spool":
+		if args != "" {
+			return true, fmt.Errorf("@runner:spool 不接受参数: %q", args)
+		}
+		d.Spool = true
+	default:
+		return true, fmt.Errorf("未知的指令: %s%s", directivePrefix, name)
+	}
+	return true, nil
+}
+
+// parseTimeout 解析超时时间，纯数字表示秒，也支持 10m、1h30m 等格式
+func parseTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("无效的超时时间: %q", value)
+	}
+	return timeout, nil
+}
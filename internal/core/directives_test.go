@@ -0,0 +1,123 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDirective(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		name    string
+		comment string
+		want    models.Directives
+		ok      bool
+		wantErr string
+	}{
+		{name: "普通注释", comment: "-- 创建用户表", ok: false},
+		{name: "超时秒数", comment: "-- @runner:timeout 600", want: models.Directives{Timeout: 600 * time.Second}, ok: true},
+		{name: "超时时长", comment: "--@runner:timeout 1h30m", want: models.Directives{Timeout: 90 * time.Minute}, ok: true},
+		{name: "忽略错误", comment: "-- @runner:ignore ORA-00955, ora-00942 1418", want: models.Directives{Ignore: []int{955, 942, 1418}}, ok: true},
+		{name: "串行", comment: "-- @runner:serial", want: models.Directives{Serial: true}, ok: true},
+		{name: "幂等", comment: "-- @runner:idempotent", want: models.Directives{Idempotent: true}, ok: true},
+		{name: "不重试", comment: "-- @runner:retries 0", want: models.Directives{Retries: &zero}, ok: true},
+		{name: "标签", comment: "-- @runner:tag seed,demo", want: models.Directives{Tags: []string{"seed", "demo"}}, ok: true},
+		{name: "最大行数", comment: "-- @runner:max_rows 0", want: models.Directives{MaxRows: &zero}, ok: true},
+		{name: "名称", comment: "-- @runner:name active_users.v2", want: models.Directives{Name: "active_users.v2"}, ok: true},
+		{name: "写入文件", comment: "-- @runner:spool", want: models.Directives{Spool: true}, ok: true},
+		{name: "未知指令", comment: "-- @runner:parallel", ok: true, wantErr: "未知的指令: @runner:parallel"},
+		{name: "无效超时", comment: "-- @runner:timeout soon", ok: true, wantErr: "无效的超时时间"},
+		{name: "负数重试", comment: "-- @runner:retries -1", ok: true, wantErr: "无效的重试次数"},
+		{name: "无效最大行数", comment: "-- @runner:max_rows all", ok: true, wantErr: "无效的最大行数"},
+		{name: "无效错误码", comment: "-- @runner:ignore PLS-00201", ok: true, wantErr: "无效的错误码"},
+		{name: "串行带参数", comment: "-- @runner:serial yes", ok: true, wantErr: "不接受参数"},
+		{name: "幂等带参数", comment: "-- @runner:idempotent yes", ok: true, wantErr: "不接受参数"},
+		{name: "名称包含路径", comment: "-- @runner:name ../users", ok: true, wantErr: "无效的语句名称"},
+		{name: "名称包含空格", comment: "-- @runner:name active users", ok: true, wantErr: "无效的语句名称"},
+		{name: "缺少名称", comment: "-- @runner:name", ok: true, wantErr: "无效的语句名称"},
+		{name: "写入文件带参数", comment: "-- @runner:spool out.csv", ok: true, wantErr: "不接受参数"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.Directives
+			ok, err := parseDirective(tt.comment, &got)
+			assert.Equal(t, tt.ok, ok)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseDirectives(t *testing.T) {
+	content := `-- @runner:timeout 600
+-- @runner:ignore ORA-00955
+-- 普通注释不影响指令
+CREATE TABLE t (id NUMBER);
+SELECT * FROM t; -- @runner:serial 在语句之后，作用于下一条语句
+-- @runner:serial
+-- @runner:tag seed
+INSERT INTO t VALUES (1);
+-- @runner:retries 0
+PROMPT 命令会清除待应用的指令
+SELECT 1 FROM dual;
+`
+	parser := ParseReader(strings.NewReader(content), ParseOptions{})
+	defer parser.Close()
+
+	var tasks []models.SQLTask
+	for parser.Next() {
+		tasks = append(tasks, parser.Task())
+	}
+	require.NoError(t, parser.Err())
+	require.Len(t, tasks, 5)
+
+	assert.Equal(t, 600*time.Second, tasks[0].Directives.Timeout)
+	assert.Equal(t, []int{955}, tasks[0].Directives.Ignore)
+	assert.Equal(t, models.Directives{}, tasks[1].Directives)
+	assert.True(t, tasks[2].Directives.Serial)
+	assert.Equal(t, []string{"seed"}, tasks[2].Directives.Tags)
+	assert.Equal(t, models.Directives{}, tasks[3].Directives)
+	assert.Equal(t, models.Directives{}, tasks[4].Directives)
+
+	t.Run("无效指令", func(t *testing.T) {
+		parser := ParseReader(strings.NewReader("SELECT 1 FROM dual;\n-- @runner:retry 1\nSELECT 2 FROM dual;\n"), ParseOptions{Filename: "deploy.sql"})
+		defer parser.Close()
+		for parser.Next() {
+		}
+		require.Error(t, parser.Err())
+		assert.Contains(t, parser.Err().Error(), "deploy.sql: 第 2 行: 未知的指令: @runner:retry")
+	})
+
+	t.Run("重复的语句名称", func(t *testing.T) {
+		parser := ParseReader(strings.NewReader("-- @runner:name users\nSELECT 1 FROM dual;\n-- @runner:name users\nSELECT 2 FROM dual;\n"), ParseOptions{Filename: "export.sql"})
+		defer parser.Close()
+		for parser.Next() {
+		}
+		require.Error(t, parser.Err())
+		assert.Contains(t, parser.Err().Error(), "export.sql: 第 4 行: 语句名称 users 已在 export.sql 第 2 行 使用")
+	})
+}
+
+func TestOraErrorCode(t *testing.T) {
+	code, ok := oraErrorCode(errors.New("查询执行失败: ORA-00942: table or view does not exist"))
+	assert.True(t, ok)
+	assert.Equal(t, 942, code)
+
+	_, ok = oraErrorCode(errors.New("connection refused"))
+	assert.False(t, ok)
+
+	assert.True(t, models.Directives{Ignore: []int{955, 942}}.Ignores(942))
+	assert.False(t, models.Directives{}.Ignores(942))
+}
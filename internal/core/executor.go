@@ -4,14 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	_ "github.com/godror/godror"
+	"github.com/godror/godror"
 	"github.com/iyuangang/oracle-sql-runner/internal/config"
 	"github.com/iyuangang/oracle-sql-runner/internal/db"
 	"github.com/iyuangang/oracle-sql-runner/internal/utils"
+	"github.com/iyuangang/oracle-sql-runner/pkg/formatter"
 	"github.com/iyuangang/oracle-sql-runner/pkg/models"
 )
 
@@ -21,6 +26,12 @@ type Executor struct {
 	logger  *utils.Logger
 	config  *config.Config
 	metrics *utils.Metrics
+	defines map[string]string
+	dbName  string
+	out     io.Writer // 脚本输出，默认为标准输出
+	script  string    // 正在执行的脚本名称
+	// spoolSubdir 结果文件目录下的子目录，在多个数据库上执行时为数据库名称，避免相互覆盖
+	spoolSubdir string
 }
 
 // NewExecutor 创建新的执行器
@@ -40,26 +51,43 @@ func NewExecutor(cfg *config.Config, dbName string, logger *utils.Logger) (*Exec
 		logger:  logger,
 		config:  cfg,
 		metrics: utils.NewMetrics(),
+		defines: dbConfig.Defines,
+		dbName:  dbName,
+		out:     os.Stdout,
 	}, nil
 }
 
+// SetOutput 设置脚本输出写入的位置
+func (e *Executor) SetOutput(w io.Writer) {
+	e.out = w
+}
+
 // ExecuteFile 执行SQL文件
 func (e *Executor) ExecuteFile(path string) *models.Result {
-	e.logger.Info("开始执行SQL文件", "file", path)
-	e.metrics.Start()
-
-	// 解析SQL文件
-	tasks, err := ParseFile(path)
+	file, err := os.Open(path)
 	if err != nil {
-		e.logger.Error("解析SQL文件失败", "error", err)
+		e.logger.Error("打开SQL文件失败", "error", err)
 		return models.NewErrorResult(err)
 	}
+	defer file.Close()
 
-	// 执行SQL任务
-	result := e.executeParallel(tasks)
+	return e.ExecuteReader(file, path)
+}
+
+// ExecuteReader 执行从 r 读取的SQL脚本，name 用于日志和错误信息
+func (e *Executor) ExecuteReader(r io.Reader, name string) *models.Result {
+	e.logger.Info("开始执行SQL文件", "file", name, "database", e.dbName)
+	e.script = name
+	e.metrics.Start()
+
+	// 边解析边执行SQL任务
+	parser := ParseReader(r, ParseOptions{Filename: name, Defines: e.defines})
+	defer parser.Close()
+	result := e.executeScript(parser)
 
 	e.metrics.End()
 	e.logger.Info("SQL文件执行完成",
+		"database", e.dbName,
 		"success", result.Success,
 		"failed", result.Failed,
 		"duration", e.metrics.Duration())
@@ -68,35 +96,156 @@ func (e *Executor) ExecuteFile(path string) *models.Result {
 	return result
 }
 
-// 添加一个新的结构体来存储输出
-type outputCapture struct {
-	mu      sync.Mutex
-	outputs []string
+// taskResult 定义任务执行结果
+type taskResult struct {
+	task     models.SQLTask
+	err      error
+	stats    taskStats
+	duration time.Duration
+	output   []string       // DBMS_OUTPUT 内容
+	warnings []compileError // PL/SQL 编译警告
 }
 
-func (o *outputCapture) Write(p []byte) (n int, err error) {
-	o.mu.Lock()
-	defer o.mu.Unlock()
-	o.outputs = append(o.outputs, string(p))
-	return len(p), nil
+// taskStats 单条语句执行过程中的统计信息
+type taskStats struct {
+	rowsAffected int64
+	rowsReturned int64
+	retries      int
+	truncated    bool   // 查询达到最大行数
+	spoolFile    string // 查询结果写入的文件
 }
 
-func (o *outputCapture) Print() {
-	o.mu.Lock()
-	defer o.mu.Unlock()
-	for _, output := range o.outputs {
-		fmt.Print(output)
+// outputWindow 每个工作协程允许领先最早未完成任务的任务数，限制等待按顺序输出的缓存
+const outputWindow = 4
+
+// maxBatchSize 每批并行执行的最大语句数，避免流式执行时在内存中积累过多语句
+const maxBatchSize = 1000
+
+// executeScript 按顺序处理SQL*Plus命令和SQL语句
+//
+// 串行模式下按脚本顺序逐条执行；并行和 dag 模式下命令之间的SQL语句成批并发执行，
+// 带有 @runner:serial 指令的语句仍单独执行。--tx file/statement 时整个脚本在同一事务中串行执行。
+func (e *Executor) executeScript(parser *Parser) *models.Result {
+	result := models.NewResult()
+	out := e.out
+	if out == nil {
+		out = os.Stdout
 	}
-}
+	state := newScriptState(out)
+	for name, value := range e.defines {
+		state.defines[strings.ToUpper(name)] = value
+	}
+	defer func() {
+		e.closeSession(state)
+		if err := state.close(); err != nil {
+			e.logger.Warn("关闭SPOOL文件失败", "error", err)
+		}
+	}()
 
-// taskResult 定义任务执行结果
-type taskResult struct {
-	task models.SQLTask
-	err  error
+	if e.usesTx() {
+		tx, err := e.beginTx(state)
+		if err != nil {
+			e.logger.Error("开始事务失败", "error", err)
+			result.AddError(models.SQLTask{Filename: parser.sp.filename}, fmt.Errorf("开始事务失败: %w", err))
+			return result
+		}
+		state.tx = tx
+	}
+
+	// 执行收集到的SQL语句，file 模式下第一条语句失败后中止脚本并回滚
+	var batch []models.SQLTask
+	aborted := false
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		res := e.executeParallel(batch, state)
+		result.Merge(res)
+		if res.Failed > 0 {
+			state.fail(state.sqlError, res.LastError())
+			aborted = e.config.Tx == config.TxFile
+		}
+		batch = batch[:0]
+	}
+	stopped := func() bool {
+		return state.exited || aborted
+	}
+
+	serial := e.usesTx() || (e.config.Mode != config.ModeParallel && e.config.Mode != config.ModeDAG)
+	skipped := 0
+	for parser.Next() {
+		task := parser.Task()
+		switch {
+		case task.Type == models.SQLTypeCommand:
+			flush()
+			if stopped() {
+				skipped++
+				break
+			}
+			if err := e.executeCommand(task, state); err != nil {
+				e.logger.Error("SQL*Plus命令执行失败", "command", task.SQL, "line", task.LineNum, "error", err)
+				result.AddError(task, err)
+				state.fail(state.osError, err)
+			} else {
+				result.AddSuccess()
+			}
+		case serial || task.Directives.Serial || isSessionStatement(task):
+			// 串行执行的语句等待之前的语句完成后单独执行
+			flush()
+			if stopped() {
+				skipped++
+				break
+			}
+			batch = append(batch, task)
+			flush()
+		default:
+			// 并行和 dag 模式下收集到下一条命令之前的SQL语句
+			batch = append(batch, task)
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		}
+
+		if stopped() {
+			break
+		}
+	}
+
+	parseErr := parser.Err()
+	if parseErr != nil {
+		// 解析失败时不再执行尚未提交的语句
+		e.logger.Error("解析SQL文件失败", "error", parseErr)
+		result.AddError(models.SQLTask{Filename: parser.sp.filename}, parseErr)
+		skipped += len(batch)
+	} else if !stopped() {
+		flush()
+	}
+
+	if stopped() {
+		// 继续解析剩余内容以统计跳过的语句
+		for parser.Next() {
+			skipped++
+		}
+	}
+	if state.exited {
+		result.Exited = true
+		result.ExitCode = state.exitCode
+		e.logger.Info("脚本提前退出", "exit_code", state.exitCode, "skipped", skipped)
+	}
+	result.AddSkipped(skipped)
+
+	// file 模式下出错或解析失败时回滚，EXIT ROLLBACK 时回滚，其他情况提交
+	commit := !aborted && !state.rollback && (parseErr == nil || e.config.Tx == config.TxStatement)
+	if err := e.endTx(state, commit); err != nil {
+		e.logger.Error("结束事务失败", "error", err)
+		result.AddError(models.SQLTask{Filename: parser.sp.filename}, err)
+	}
+
+	return result
 }
 
-// executeParallel 并行执行SQL任务
-func (e *Executor) executeParallel(tasks []models.SQLTask) *models.Result {
+// executeParallel 并行执行SQL任务，dag 模式下按语句读写的对象保证有依赖的语句按顺序执行
+func (e *Executor) executeParallel(tasks []models.SQLTask, state *scriptState) *models.Result {
 	result := models.NewResult()
 	if len(tasks) == 0 {
 		return result
@@ -108,44 +257,107 @@ func (e *Executor) executeParallel(tasks []models.SQLTask) *models.Result {
 		workerCount = len(tasks)
 	}
 
-	// 创建任务通道
-	taskChan := make(chan models.SQLTask, len(tasks))
-	for _, task := range tasks {
-		taskChan <- task
+	// 语句在脚本会话或其事务中执行，并行时其他工作协程重放脚本会话的设置
+	if state.tx == nil {
+		if _, err := e.scriptSession(state); err != nil {
+			e.logger.Error("建立数据库会话失败", "error", err)
+			for _, task := range tasks {
+				result.AddError(task, err)
+			}
+			return result
+		}
 	}
-	close(taskChan)
+
+	// 按依赖关系调度任务，dag 模式下只有互不依赖的语句并发执行
+	var deps [][]int
+	if e.config.Mode == config.ModeDAG {
+		deps = dependencies(tasks)
+	}
+	// 按脚本顺序输出，限制等待输出的任务数
+	sched := newScheduler(len(tasks), deps, outputWindow*workerCount)
+	stream := newOrderedOutput(state.writer(), len(tasks))
 
 	// 创建结果通道
 	resultChan := make(chan taskResult, len(tasks))
 
-	// 创建输出捕获器
-	output := &outputCapture{}
-
 	// 启动工作协程
 	var wg sync.WaitGroup
-	for i := 0; i < workerCount; i++ {
+	for w := 0; w < workerCount; w++ {
 		wg.Add(1)
-		go func() {
+		go func(w int) {
 			defer wg.Done()
-			for task := range taskChan {
-				// 增加超时时间，默认设置为30秒
+			q, release, connErr := e.workerQuerier(state, w)
+			defer release()
+			for {
+				i, ok := sched.take()
+				if !ok {
+					return
+				}
+				task := tasks[i]
+				output := stream.task(i)
+				if connErr != nil {
+					resultChan <- taskResult{task: task, err: connErr}
+					e.metrics.AddQuery(0, false)
+					output.close()
+					sched.done(i)
+					continue
+				}
+
+				// 增加超时时间，默认设置为30秒，语句指令优先
 				timeout := 30 * time.Second
-				if e.config.Timeout > 0 {
+				if task.Directives.Timeout > 0 {
+					timeout = task.Directives.Timeout
+				} else if e.config.Timeout > 0 {
 					timeout = time.Duration(e.config.Timeout) * time.Second
 				}
 
 				ctx, cancel := context.WithTimeout(context.Background(), timeout)
 
 				start := time.Now()
-				err := e.executeTaskWithOutput(ctx, task, output)
+				var stats taskStats
+				var err error
+				if state.tx != nil {
+					stats, err = e.executeInTx(ctx, state, task, output)
+				} else {
+					stats, err = e.executeTaskWithOutput(ctx, q, task, output)
+				}
+				if state.tx != nil {
+					// 连接中断后事务可能已在新连接上重新开始
+					q = state.tx
+				}
+				var warnings []compileError
+				if err == nil {
+					warnings, err = e.checkCompileErrors(ctx, q, task, output)
+				}
+				var lines []string
+				if producesServerOutput(task) {
+					// 执行失败时同样读取，保留异常前输出的内容
+					lines = e.drainServerOutput(q, state, output)
+				}
+				if err == nil && isSessionStatement(task) {
+					// 会话设置语句单独执行，此时没有其他工作协程访问脚本会话
+					state.session.setup = append(state.session.setup, task.SQL)
+				}
 				duration := time.Since(start)
+				if state.timing {
+					fmt.Fprintf(output, "已用时间: %s\n", duration.Round(time.Millisecond))
+				}
 
 				cancel()
 
-				resultChan <- taskResult{task: task, err: err}
+				resultChan <- taskResult{
+					task:     task,
+					err:      withPosition(task, err),
+					stats:    stats,
+					duration: duration,
+					output:   lines,
+					warnings: warnings,
+				}
 				e.metrics.AddQuery(duration, err == nil)
+				output.close()
+				sched.done(i)
 			}
-		}()
+		}(w)
 	}
 
 	// 等待所有任务完成
@@ -157,8 +369,9 @@ func (e *Executor) executeParallel(tasks []models.SQLTask) *models.Result {
 	// 处理结果
 	result = processResults(resultChan)
 
-	// 打印捕获的输出
-	output.Print()
+	if err := stream.Err(); err != nil {
+		e.logger.Warn("写入输出失败", "error", err)
+	}
 
 	return result
 }
@@ -169,32 +382,39 @@ func processResults(resultChan chan taskResult) *models.Result {
 
 	// 处理所有任务的结果
 	for res := range resultChan {
-		if res.err != nil {
-			result.AddError(res.task, res.err)
-		} else {
-			result.AddSuccess()
+		for _, w := range res.warnings {
+			result.AddWarning(res.task, w.message(res.task), w.sourcePosition(res.task))
 		}
+		result.AddStatement(res.task, models.StatementResult{
+			RowsAffected: res.stats.rowsAffected,
+			RowsReturned: res.stats.rowsReturned,
+			Truncated:    res.stats.truncated,
+			Duration:     res.duration,
+			Retries:      res.stats.retries,
+			Output:       res.output,
+			SpoolFile:    res.stats.spoolFile,
+		}, res.err)
 	}
 
+	// 并行执行时按完成顺序收到结果，恢复为脚本顺序
+	sort.SliceStable(result.Statements, func(i, j int) bool {
+		return result.Statements[i].Index < result.Statements[j].Index
+	})
+
 	return result
 }
 
 // executeTask 执行单个SQL任务
 func (e *Executor) executeTask(ctx context.Context, task models.SQLTask) error {
-	maxRetries := e.config.MaxRetries
-	var lastErr error
-	var err error
-
-	for retry := 0; retry < maxRetries; retry++ {
-		if retry > 0 {
-			// 重试前等待一小段时间
-			time.Sleep(time.Duration(retry*100) * time.Millisecond)
-		}
+	policy := e.retryPolicy(task)
+	start := time.Now()
 
+	for retry := 1; ; retry++ {
+		var err error
 		switch task.Type {
 		case models.SQLTypeQuery:
 			err = e.executeQuery(ctx, task.SQL)
-		case models.SQLTypePLSQL:
+		case models.SQLTypePLSQL, models.SQLTypeBlock:
 			err = e.executePLSQL(ctx, task.SQL)
 		default:
 			_, err = e.pool.ExecContext(ctx, task.SQL)
@@ -204,25 +424,29 @@ func (e *Executor) executeTask(ctx context.Context, task models.SQLTask) error {
 			return nil
 		}
 
-		lastErr = err
+		// 如果是不可重试的错误，直接返回
+		delay, ok := policy.next(retry, time.Since(start), err)
+		if !ok {
+			return err
+		}
+		if connectionLost(err) && !replaySafe(task) {
+			return outcomeUnknown(err)
+		}
 		e.logger.Warn("SQL执行失败，准备重试",
 			"sql", task.SQL,
 			"line", task.LineNum,
-			"retry", retry+1,
+			"retry", retry,
+			"delay", delay,
 			"error", err)
-
-		// 如果是不可重试的错误，直接返回
-		if !isRetryableError(err) {
+		if !sleep(ctx, delay) {
 			return err
 		}
 	}
-
-	return lastErr
 }
 
 // executeQuery 执行查询
 func (e *Executor) executeQuery(ctx context.Context, sql string) error {
-	rows, err := e.pool.QueryContext(ctx, sql)
+	rows, err := e.pool.QueryContext(ctx, sql, e.queryOptions()...)
 	if err != nil {
 		return err
 	}
@@ -237,250 +461,160 @@ func (e *Executor) executePLSQL(ctx context.Context, sql string) error {
 	return err
 }
 
-// isRetryableError 判断是否可重试的错误
-func isRetryableError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	// Oracle 错误码映射
-	oracleErrors := map[int]bool{
-		1033:  true, // ORACLE initialization or shutdown in progress
-		1034:  true, // ORACLE not available
-		1089:  true, // immediate shutdown in progress
-		1090:  true, // shutdown in progress
-		1092:  true, // ORACLE instance terminated
-		3113:  true, // end-of-file on communication channel
-		3114:  true, // not connected to ORACLE
-		12153: true, // TNS:not connected
-		12154: true, // TNS:could not resolve service name
-		12170: true, // TNS:Connect timeout occurred
-		12171: true, // TNS:could not resolve connect identifier
-		12257: true, // TNS:protocol adapter not loadable
-		12514: true, // TNS:listener does not currently know of service requested
-		12528: true, // TNS:listener: all appropriate instances are blocking new connections
-		12537: true, // TNS:connection closed
-		12541: true, // TNS:no listener
-		12571: true, // TNS:packet writer failure
-	}
-
-	errStr := err.Error()
-	// 提取错误码
-	var errorCode int
-	_, scanErr := fmt.Sscanf(errStr, "ORA-%d:", &errorCode)
-	if scanErr == nil {
-		return oracleErrors[errorCode]
-	}
-
-	// 检查网络相关错误
-	networkErrors := []string{
-		"connection refused",
-		"connection reset",
-		"connection timed out",
-		"no route to host",
-		"network is unreachable",
-	}
-
-	for _, netErr := range networkErrors {
-		if strings.Contains(strings.ToLower(errStr), netErr) {
-			return true
-		}
-	}
-
-	return false
-}
-
 // Close 关闭执行器
 func (e *Executor) Close() error {
 	return e.pool.Close()
 }
 
-// printQueryResults 打印查询结果
+// printQueryResults 以表格形式打印查询结果
 func printQueryResults(rows *sql.Rows) error {
-	// 获取列信息
-	columns, err := rows.Columns()
-	if err != nil {
-		return err
-	}
-
-	// 打印列头
-	for i, col := range columns {
-		if i > 0 {
-			fmt.Print("\t")
-		}
-		fmt.Print(col)
-	}
-	fmt.Println()
-	fmt.Println(strings.Repeat("-", 80))
-
-	// 准备扫描目标
-	values := make([]interface{}, len(columns))
-	scanArgs := make([]interface{}, len(columns))
-	for i := range values {
-		scanArgs[i] = &values[i]
-	}
-
-	// 打印数据行
-	rowCount := 0
-	for rows.Next() {
-		err := rows.Scan(scanArgs...)
-		if err != nil {
-			return err
-		}
-
-		for i, value := range values {
-			if i > 0 {
-				fmt.Print("\t")
-			}
-			switch v := value.(type) {
-			case nil:
-				fmt.Print("NULL")
-			case []byte:
-				fmt.Print(string(v))
-			case time.Time:
-				fmt.Print(v.Format("2006-01-02 15:04:05"))
-			default:
-				fmt.Print(v)
-			}
-		}
-		fmt.Println()
-		rowCount++
-	}
-
-	// 打印统计信息
-	fmt.Printf("\n共返回 %d 行数据\n", rowCount)
-	fmt.Println(strings.Repeat("-", 80))
-
-	return rows.Err()
+	_, err := formatter.WriteRows(formatter.NewTable(os.Stdout), rows)
+	return err
 }
 
 // executeTaskWithOutput 执行单个SQL任务并捕获输出
-func (e *Executor) executeTaskWithOutput(ctx context.Context, task models.SQLTask, output *outputCapture) error {
-	maxRetries := 3
-	var lastErr error
-
-	for retry := 0; retry < maxRetries; retry++ {
-		if retry > 0 {
-			time.Sleep(time.Duration(retry*100) * time.Millisecond)
-		}
+func (e *Executor) executeTaskWithOutput(ctx context.Context, q querier, task models.SQLTask, output *outputCapture) (taskStats, error) {
+	var stats taskStats
+	policy := e.retryPolicy(task)
+	if _, ok := q.(*sql.Tx); ok {
+		// 可重试的错误均为连接错误，事务已随连接失效，重试没有意义
+		policy.maxRetries = 0
+	}
+	start := time.Now()
 
+	for {
 		fmt.Fprintf(output, "\n执行任务: Type=%v, SQL=%v\n", task.Type, task.SQL)
 
 		var err error
 		switch task.Type {
 		case models.SQLTypeQuery:
 			fmt.Fprintf(output, "执行查询语句\n")
-			err = e.executeQueryWithOutput(ctx, task.SQL, output)
-		case models.SQLTypePLSQL:
+			err = e.executeQueryWithOutput(ctx, q, task, &stats, output)
+		case models.SQLTypePLSQL, models.SQLTypeBlock:
 			fmt.Fprintf(output, "执行PL/SQL块\n")
-			err = e.executePLSQL(ctx, task.SQL)
+			_, err = q.ExecContext(ctx, task.SQL)
 		default:
 			fmt.Fprintf(output, "执行普通SQL\n")
-			_, err = e.pool.ExecContext(ctx, task.SQL)
+			var res sql.Result
+			if res, err = q.ExecContext(ctx, task.SQL); err == nil && task.Type == models.SQLTypeDML {
+				if n, rowsErr := res.RowsAffected(); rowsErr == nil {
+					stats.rowsAffected = n
+					fmt.Fprintf(output, "影响 %d 行\n", n)
+				}
+			}
 		}
 
 		if err == nil {
-			return nil
+			return stats, nil
 		}
 
-		lastErr = err
+		if code, ok := oraErrorCode(err); ok && task.Directives.Ignores(code) {
+			fmt.Fprintf(output, "已忽略错误 ORA-%05d: %v\n", code, err)
+			return stats, nil
+		}
 		fmt.Fprintf(output, "运行失败, 错误: %v\n", err)
 
-		if !isRetryableError(lastErr) {
-			return lastErr
+		delay, ok := policy.next(stats.retries+1, time.Since(start), err)
+		lost := connectionLost(err)
+		if !ok {
+			if lost {
+				e.recoverSession(q, output)
+			}
+			return stats, err
 		}
-	}
-
-	return lastErr
-}
-
-// executeQueryWithOutput 执行查询并捕获输出
-func (e *Executor) executeQueryWithOutput(ctx context.Context, sql string, output *outputCapture) error {
-	fmt.Fprintf(output, "\n开始执行查询: %v\n", sql)
-
-	// 添加错误处理和重试逻辑
-	var lastErr error
-	for retry := 0; retry < 3; retry++ {
-		if retry > 0 {
-			fmt.Fprintf(output, "重试查询 (第 %d 次)\n", retry+1)
-			time.Sleep(time.Duration(retry*100) * time.Millisecond)
+		if lost && !replaySafe(task) {
+			fmt.Fprintf(output, "连接中断，语句可能已经提交，不再重试\n")
+			e.recoverSession(q, output)
+			return stats, outcomeUnknown(err)
+		}
+		stats.retries++
+		e.logger.Warn("SQL执行失败，准备重试",
+			"file", task.Filename,
+			"line", task.LineNum,
+			"retry", stats.retries,
+			"delay", delay,
+			"error", err)
+		fmt.Fprintf(output, "%s 后第 %d 次重试\n", delay.Round(time.Millisecond), stats.retries)
+		if !sleep(ctx, delay) {
+			return stats, err
 		}
 
-		rows, err := e.pool.QueryContext(ctx, sql)
-		if err != nil {
-			lastErr = err
-			fmt.Fprintf(output, "查询执行失败: %v\n", err)
-			if ctx.Err() == context.DeadlineExceeded {
-				return fmt.Errorf("查询超时: %w", err)
+		// 可重试的错误意味着连接已失效，固定会话需要重新建立
+		if s, ok := q.(*session); ok {
+			if rcErr := s.reconnect(ctx); rcErr != nil {
+				fmt.Fprintf(output, "重新建立数据库会话失败: %v\n", rcErr)
+				return stats, err
 			}
-			continue
 		}
-		defer rows.Close()
+	}
+}
 
-		fmt.Fprintf(output, "查询执行成功，准备打印结果\n")
-		return printQueryResultsWithOutput(rows, output)
+// recoverSession 连接中断且不再重试时重新建立固定会话，之后的语句不会在失效的连接上执行
+func (e *Executor) recoverSession(q querier, output io.Writer) {
+	s, ok := q.(*session)
+	if !ok {
+		return
 	}
+	// 语句超时后 ctx 已失效，重新连接使用新的上下文
+	if err := s.reconnect(context.Background()); err != nil {
+		e.logger.Error("重新建立数据库会话失败", "error", err)
+		fmt.Fprintf(output, "重新建立数据库会话失败: %v\n", err)
+	}
+}
 
-	return fmt.Errorf("查询执行失败: %w", lastErr)
+// oraErrorCode 提取错误对应的 Oracle 错误码
+func oraErrorCode(err error) (int, bool) {
+	if oraErr, ok := godror.AsOraErr(err); ok && oraErr.Code() > 0 {
+		return oraErr.Code(), true
+	}
+	if match := oraCodePattern.FindStringSubmatch(err.Error()); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code, true
+	}
+	return 0, false
 }
 
-// printQueryResultsWithOutput 打印查询结果并捕获输出
-func printQueryResultsWithOutput(rows *sql.Rows, output *outputCapture) error {
-	fmt.Fprintf(output, "\n=== 开始打印查询结果 ===\n")
+// executeQueryWithOutput 执行查询并捕获输出，返回的行数等信息记录在 stats 中
+func (e *Executor) executeQueryWithOutput(ctx context.Context, q querier, task models.SQLTask, stats *taskStats, output *outputCapture) error {
+	fmt.Fprintf(output, "\n开始执行查询: %v\n", task.SQL)
 
-	columns, err := rows.Columns()
+	// 重试由 executeTaskWithOutput 统一处理
+	rows, err := q.QueryContext(ctx, task.SQL, e.queryOptions()...)
 	if err != nil {
-		fmt.Fprintf(output, "获取列信息失败: %v\n", err)
-		return fmt.Errorf("获取列信息失败: %w", err)
-	}
-
-	// 打印列头
-	for i, col := range columns {
-		if i > 0 {
-			fmt.Fprintf(output, "\t")
+		fmt.Fprintf(output, "查询执行失败: %v\n", err)
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("查询超时: %w", err)
 		}
-		fmt.Fprintf(output, "%s", col)
-	}
-	fmt.Fprintf(output, "\n")
-	fmt.Fprintf(output, "%s\n", strings.Repeat("-", 80))
-
-	// 准备扫描目标
-	values := make([]interface{}, len(columns))
-	scanArgs := make([]interface{}, len(columns))
-	for i := range values {
-		scanArgs[i] = &values[i]
+		return fmt.Errorf("查询执行失败: %w", err)
 	}
+	defer rows.Close()
 
-	// 打印数据行
-	rowCount := 0
-	for rows.Next() {
-		err := rows.Scan(scanArgs...)
+	limits := e.queryLimits(task)
+	if spoolFile := e.spoolPath(task); spoolFile != "" {
+		stats.rowsReturned, stats.truncated, err = e.spoolQueryResults(rows, limits, spoolFile)
 		if err != nil {
-			return fmt.Errorf("扫描行数据失败: %w", err)
+			return err
 		}
-
-		for i, value := range values {
-			if i > 0 {
-				fmt.Fprintf(output, "\t")
-			}
-			switch v := value.(type) {
-			case nil:
-				fmt.Fprintf(output, "NULL")
-			case []byte:
-				fmt.Fprintf(output, "%s", string(v))
-			case time.Time:
-				fmt.Fprintf(output, "%s", v.Format("2006-01-02 15:04:05"))
-			default:
-				fmt.Fprintf(output, "%v", v)
-			}
+		stats.spoolFile = spoolFile
+		fmt.Fprintf(output, "查询结果已写入 %s，共 %d 行数据\n", spoolFile, stats.rowsReturned)
+	} else {
+		fmt.Fprintf(output, "查询执行成功，准备打印结果\n")
+		stats.rowsReturned, stats.truncated, err = e.printQueryResultsWithOutput(rows, limits, output)
+		if err != nil {
+			return err
 		}
-		fmt.Fprintf(output, "\n")
-		rowCount++
 	}
+	if stats.truncated {
+		fmt.Fprintf(output, "已达到最大行数 %d，其余行未读取\n", limits.MaxRows)
+	}
+	return nil
+}
 
-	fmt.Fprintf(output, "\n共返回 %d 行数据\n", rowCount)
-	fmt.Fprintf(output, "%s\n", strings.Repeat("-", 80))
-	fmt.Fprintf(output, "=== 结束打印查询结果 ===\n\n")
-
-	return rows.Err()
+// printQueryResultsWithOutput 按配置的格式打印查询结果并捕获输出，返回读取的行数以及是否因达到最大行数而截断
+func (e *Executor) printQueryResultsWithOutput(rows *sql.Rows, limits formatter.Limits, output *outputCapture) (int64, bool, error) {
+	f, err := e.newFormatter(output)
+	if err != nil {
+		return 0, false, err
+	}
+	return limits.WriteRows(f, rows)
 }
@@ -2,8 +2,10 @@ package core
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -282,7 +284,7 @@ func TestParallelExecutionConcurrencySafety(t *testing.T) {
 		wg.Add(1)
 		go func(idx int) {
 			defer wg.Done()
-			results[idx] = executor.executeParallel(tasks)
+			results[idx] = executor.executeParallel(tasks, newScriptState(io.Discard))
 		}(i)
 	}
 	wg.Wait()
@@ -298,6 +300,202 @@ func TestParallelExecutionConcurrencySafety(t *testing.T) {
 	}
 }
 
+func TestExecuteTxModes(t *testing.T) {
+	cfg, logger := setupTestEnv(t)
+	executor, err := NewExecutor(cfg, "test", logger)
+	require.NoError(t, err)
+	defer executor.Close()
+
+	// 建表语句会隐式提交，在自动提交模式下准备测试表
+	setup := executor.ExecuteReader(strings.NewReader(`BEGIN
+  EXECUTE IMMEDIATE 'DROP TABLE tx_mode_test';
+EXCEPTION WHEN OTHERS THEN NULL;
+END;
+/
+CREATE TABLE tx_mode_test (id NUMBER PRIMARY KEY);
+`), "setup.sql")
+	require.Equal(t, 0, setup.Failed)
+	defer executor.ExecuteReader(strings.NewReader("DROP TABLE tx_mode_test;\n"), "cleanup.sql")
+
+	count := func(t *testing.T) int {
+		t.Helper()
+		rows, err := executor.pool.QueryContext(context.Background(), "SELECT COUNT(*) FROM tx_mode_test")
+		require.NoError(t, err)
+		defer rows.Close()
+		var n int
+		require.True(t, rows.Next())
+		require.NoError(t, rows.Scan(&n))
+		return n
+	}
+	script := `INSERT INTO tx_mode_test VALUES (1);
+INSERT INTO tx_mode_test VALUES (1);
+INSERT INTO tx_mode_test VALUES (2);
+`
+
+	t.Run("file 出错时回滚整个脚本", func(t *testing.T) {
+		executor.config.Tx = config.TxFile
+		result := executor.ExecuteReader(strings.NewReader(script), "file.sql")
+		assert.Equal(t, 1, result.Success)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, 1, result.Skipped)
+		assert.Equal(t, 0, count(t))
+	})
+
+	t.Run("statement 出错的语句回滚到保存点", func(t *testing.T) {
+		executor.config.Tx = config.TxStatement
+		result := executor.ExecuteReader(strings.NewReader(script), "statement.sql")
+		assert.Equal(t, 2, result.Success)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, 2, count(t))
+	})
+}
+
+func TestExecuteSessionPinned(t *testing.T) {
+	cfg, logger := setupTestEnv(t)
+	executor, err := NewExecutor(cfg, "test", logger)
+	require.NoError(t, err)
+	defer executor.Close()
+
+	// 会话设置在之后的串行和并行语句中都生效
+	script := `ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY"年"';
+DECLARE
+  v VARCHAR2(20) := TO_CHAR(DATE '2024-01-01');
+BEGIN
+  IF v <> '2024年' THEN
+    RAISE_APPLICATION_ERROR(-20001, v);
+  END IF;
+END;
+/
+SELECT TO_CHAR(SYSDATE) FROM DUAL;
+SELECT TO_DATE('2024年') FROM DUAL;
+SELECT TO_DATE('2025年') FROM DUAL;
+`
+	for _, mode := range []string{config.ModeSerial, config.ModeParallel} {
+		t.Run(mode, func(t *testing.T) {
+			executor.config.Mode = mode
+			result := executor.ExecuteReader(strings.NewReader(script), "session.sql")
+			assert.Equal(t, 0, result.Failed)
+			assert.Equal(t, 5, result.Success)
+		})
+	}
+}
+
+func TestExecuteServerOutput(t *testing.T) {
+	cfg, logger := setupTestEnv(t)
+	executor, err := NewExecutor(cfg, "test", logger)
+	require.NoError(t, err)
+	defer executor.Close()
+
+	script := `BEGIN
+  DBMS_OUTPUT.PUT_LINE('第一行');
+  DBMS_OUTPUT.PUT_LINE('第二行');
+END;
+/
+SELECT 1 FROM DUAL;
+SET SERVEROUTPUT OFF
+BEGIN
+  DBMS_OUTPUT.PUT_LINE('不输出');
+END;
+/
+SET SERVEROUTPUT ON
+BEGIN
+  DBMS_OUTPUT.PUT_LINE('第三行');
+END;
+/
+`
+	for _, mode := range []string{config.ModeSerial, config.ModeParallel} {
+		t.Run(mode, func(t *testing.T) {
+			executor.config.Mode = mode
+			result := executor.ExecuteReader(strings.NewReader(script), "output.sql")
+			assert.Equal(t, 0, result.Failed)
+			require.Len(t, result.Statements, 4)
+			assert.Equal(t, []string{"第一行", "第二行"}, result.Statements[0].Output)
+			assert.Equal(t, 1, result.Statements[0].StartLine)
+			assert.Empty(t, result.Statements[1].Output)
+			assert.Empty(t, result.Statements[2].Output, "SET SERVEROUTPUT OFF 后不记录输出")
+			assert.Equal(t, []string{"第三行"}, result.Statements[3].Output)
+		})
+	}
+}
+
+func TestExecuteStatementResults(t *testing.T) {
+	cfg, logger := setupTestEnv(t)
+	executor, err := NewExecutor(cfg, "test", logger)
+	require.NoError(t, err)
+	defer executor.Close()
+
+	script := `CREATE GLOBAL TEMPORARY TABLE stmt_result_test (id NUMBER) ON COMMIT PRESERVE ROWS;
+INSERT INTO stmt_result_test SELECT LEVEL FROM DUAL CONNECT BY LEVEL <= 3;
+UPDATE stmt_result_test SET id = id + 1 WHERE id > 1;
+SELECT * FROM stmt_result_test;
+SELECT * FROM missing_table;
+TRUNCATE TABLE stmt_result_test;
+DROP TABLE stmt_result_test;
+`
+	result := executor.ExecuteReader(strings.NewReader(script), "stmt.sql")
+	require.Len(t, result.Statements, 7)
+	for i, stmt := range result.Statements {
+		assert.Equal(t, i+1, stmt.Index)
+		assert.Equal(t, i+1, stmt.StartLine)
+		assert.Equal(t, "stmt.sql", stmt.File)
+	}
+	assert.Equal(t, int64(3), result.Statements[1].RowsAffected)
+	assert.Equal(t, int64(2), result.Statements[2].RowsAffected)
+	assert.Equal(t, int64(3), result.Statements[3].RowsReturned)
+	assert.Equal(t, models.SQLTypeQuery, result.Statements[3].Type)
+	require.NotNil(t, result.Statements[4].Error)
+	assert.Contains(t, result.Statements[4].Error.Message, "ORA-00942")
+	assert.Zero(t, result.Statements[4].Retries)
+	assert.Nil(t, result.Statements[5].Error)
+}
+
+func TestExecuteCompileErrors(t *testing.T) {
+	cfg, logger := setupTestEnv(t)
+	executor, err := NewExecutor(cfg, "test", logger)
+	require.NoError(t, err)
+	defer executor.Close()
+	defer executor.ExecuteReader(strings.NewReader("DROP PROCEDURE compile_err_test;\nDROP PROCEDURE compile_warn_test;\n"), "cleanup.sql")
+
+	script := `CREATE OR REPLACE PROCEDURE compile_err_test AS
+BEGIN
+  undefined_var := 1;
+END;
+/
+ALTER SESSION SET PLSQL_WARNINGS = 'ENABLE:ALL';
+CREATE OR REPLACE PROCEDURE compile_warn_test AS
+BEGIN
+  IF 1 = 0 THEN
+    NULL;
+  END IF;
+END;
+/
+`
+	result := executor.ExecuteReader(strings.NewReader(script), "compile.sql")
+	require.Equal(t, 1, result.Failed)
+	assert.Equal(t, 2, result.Success)
+	assert.Contains(t, result.Errors[0].Message, "PLS-00201")
+	require.NotNil(t, result.Errors[0].Position)
+	assert.Equal(t, 3, result.Errors[0].Position.Line)
+	assert.NotEmpty(t, result.Warnings)
+}
+
+func TestExecuteCompileErrorsCurrentSchema(t *testing.T) {
+	cfg, logger := setupTestEnv(t)
+	executor, err := NewExecutor(cfg, "test", logger)
+	require.NoError(t, err)
+	defer executor.Close()
+
+	// 未指定模式名的程序单元创建在当前模式中，编译错误从 ALL_ERRORS 中按当前模式查询
+	schema := strings.ToUpper(cfg.Databases["test"].User)
+	defer executor.ExecuteReader(strings.NewReader("DROP PROCEDURE compile_schema_test;\n"), "cleanup.sql")
+
+	script := "ALTER SESSION SET CURRENT_SCHEMA = " + schema + ";\n" +
+		"CREATE OR REPLACE PROCEDURE compile_schema_test AS\nBEGIN\n  undefined_var := 1;\nEND;\n/\n"
+	result := executor.ExecuteReader(strings.NewReader(script), "compile.sql")
+	require.Equal(t, 1, result.Failed)
+	assert.Contains(t, result.Errors[0].Message, "PLS-00201")
+}
+
 func TestPrintQueryResults(t *testing.T) {
 	cfg, logger := setupTestEnv(t)
 	executor, err := NewExecutor(cfg, "test", logger)
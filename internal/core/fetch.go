@@ -0,0 +1,50 @@
+package core
+
+import (
+	"io"
+
+	"github.com/godror/godror"
+	"github.com/iyuangang/oracle-sql-runner/pkg/formatter"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// queryOptions 返回执行查询时传给驱动的选项
+//
+// LOB 以 io.Reader 返回，按 lob_limit 读取，避免大字段完整读入内存。
+func (e *Executor) queryOptions() []interface{} {
+	opts := []interface{}{godror.LobAsReader()}
+	if e.config.BatchSize > 0 {
+		opts = append(opts, godror.FetchArraySize(e.config.BatchSize))
+	}
+	if e.config.PrefetchRows > 0 {
+		opts = append(opts, godror.PrefetchCount(e.config.PrefetchRows))
+	}
+	return opts
+}
+
+// queryLimits 返回读取查询结果时的限制，语句的 @runner:max_rows 优先于配置
+func (e *Executor) queryLimits(task models.SQLTask) formatter.Limits {
+	limits := formatter.Limits{MaxRows: int64(e.config.MaxRows)}
+	if task.Directives.MaxRows != nil {
+		limits.MaxRows = int64(*task.Directives.MaxRows)
+	}
+	if e.config.LOBLimit > 0 {
+		limits.LOBBytes = e.config.LOBLimit
+	}
+	return limits
+}
+
+// newFormatter 按配置的输出格式和显示方式创建格式化器
+func (e *Executor) newFormatter(w io.Writer) (formatter.ResultFormatter, error) {
+	d := e.config.Display
+	r := formatter.Renderer{
+		DateFormat:        d.DateFormat,
+		TimestampFormat:   d.TimestampFormat,
+		TimestampTZFormat: d.TimestampTZFormat,
+		Null:              formatter.DefaultNull,
+	}
+	if d.Null != nil {
+		r.Null = *d.Null
+	}
+	return formatter.NewWithRenderer(e.config.Format, w, r)
+}
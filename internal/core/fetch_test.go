@@ -0,0 +1,61 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/internal/config"
+	"github.com/iyuangang/oracle-sql-runner/pkg/formatter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxRows  int
+		lobLimit int
+		sql      string
+		want     formatter.Limits
+	}{
+		{name: "不限制", lobLimit: -1, sql: "SELECT * FROM t;", want: formatter.Limits{}},
+		{name: "全局配置", maxRows: 100, lobLimit: 4096, sql: "SELECT * FROM t;", want: formatter.Limits{MaxRows: 100, LOBBytes: 4096}},
+		{name: "语句指令优先", maxRows: 100, lobLimit: 4096, sql: "-- @runner:max_rows 10\nSELECT * FROM t;", want: formatter.Limits{MaxRows: 10, LOBBytes: 4096}},
+		{name: "语句不限制行数", maxRows: 100, lobLimit: 4096, sql: "-- @runner:max_rows 0\nSELECT * FROM t;", want: formatter.Limits{LOBBytes: 4096}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Executor{config: &config.Config{MaxRows: tt.maxRows, LOBLimit: tt.lobLimit}}
+			assert.Equal(t, tt.want, e.queryLimits(parseOne(t, tt.sql)))
+		})
+	}
+}
+
+func TestQueryOptions(t *testing.T) {
+	e := &Executor{config: &config.Config{}}
+	assert.Len(t, e.queryOptions(), 1, "总是以读取器返回LOB")
+
+	e.config.BatchSize = 500
+	e.config.PrefetchRows = 100
+	assert.Len(t, e.queryOptions(), 3)
+}
+
+func TestNewFormatter(t *testing.T) {
+	empty := ""
+	e := &Executor{config: &config.Config{
+		Format:  formatter.FormatCSV,
+		Display: config.DisplayConfig{DateFormat: "2006/01/02", Null: &empty},
+	}}
+
+	var buf bytes.Buffer
+	f, err := e.newFormatter(&buf)
+	require.NoError(t, err)
+	require.IsType(t, &formatter.CSV{}, f)
+	assert.Equal(t, formatter.Renderer{DateFormat: "2006/01/02"}, f.(*formatter.CSV).Renderer)
+
+	e.config.Display.Null = nil
+	f, err = e.newFormatter(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, formatter.DefaultNull, f.(*formatter.CSV).Renderer.Null)
+}
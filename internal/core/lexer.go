@@ -0,0 +1,504 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// tokenKind 词法单元类型
+type tokenKind int
+
+const (
+	tokenEOF          tokenKind = iota
+	tokenSpace                  // 空白（不含换行）
+	tokenNewline                // 换行
+	tokenWord                   // 关键字或未加引号的标识符
+	tokenQuotedIdent            // 双引号标识符
+	tokenString                 // 字符串字面量，包括 N'...' 和 q'[...]'
+	tokenNumber                 // 数字
+	tokenLineComment            // -- 行注释
+	tokenBlockComment           // /* */ 块注释
+	tokenSemicolon              // 分号
+	tokenSlash                  // 单独成行的 / 结束符
+	tokenSymbol                 // 其他符号
+)
+
+// token 词法单元
+type token struct {
+	kind      tokenKind
+	text      string
+	line      int  // 起始行号
+	col       int  // 起始列号（按字符计，从1开始）
+	endLine   int  // 结束行号
+	lineStart bool // 所在行中此前只有空白
+}
+
+// significant 判断词法单元是否属于语句内容（非空白、非注释）
+func (t token) significant() bool {
+	switch t.kind {
+	case tokenSpace, tokenNewline, tokenLineComment, tokenBlockComment:
+		return false
+	}
+	return true
+}
+
+// upper 返回大写形式的词法单元文本
+func (t token) upper() string {
+	return strings.ToUpper(t.text)
+}
+
+// lexer Oracle SQL 脚本词法分析器
+type lexer struct {
+	r         *bufio.Reader
+	buf       []rune // 预读缓冲
+	eof       bool
+	line      int
+	col       int
+	lineStart bool // 当前行到目前为止只有空白
+}
+
+// newLexer 创建词法分析器
+func newLexer(r io.Reader) *lexer {
+	return &lexer{
+		r:         bufio.NewReader(r),
+		line:      1,
+		col:       1,
+		lineStart: true,
+	}
+}
+
+// fill 确保预读缓冲中至少有 n 个字符（文件结束时可能不足）
+func (l *lexer) fill(n int) error {
+	for len(l.buf) < n && !l.eof {
+		ch, _, err := l.r.ReadRune()
+		if errors.Is(err, io.EOF) {
+			l.eof = true
+			break
+		}
+		if err != nil {
+			return err
+		}
+		l.buf = append(l.buf, ch)
+	}
+	return nil
+}
+
+// peek 查看第 i 个预读字符，不存在时返回 0
+func (l *lexer) peek(i int) (rune, error) {
+	if err := l.fill(i + 1); err != nil {
+		return 0, err
+	}
+	if i >= len(l.buf) {
+		return 0, nil
+	}
+	return l.buf[i], nil
+}
+
+// advance 消费一个字符并写入 sb
+func (l *lexer) advance(sb *strings.Builder) rune {
+	ch := l.buf[0]
+	l.buf = l.buf[1:]
+	sb.WriteRune(ch)
+	if ch == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return ch
+}
+
+// next 读取下一个词法单元
+func (l *lexer) next() (token, error) {
+	ch, err := l.peek(0)
+	if err != nil {
+		return token{}, err
+	}
+
+	tok := token{line: l.line, col: l.col}
+	if ch == 0 && len(l.buf) == 0 {
+		tok.kind = tokenEOF
+		tok.endLine = l.line
+		return tok, nil
+	}
+
+	var sb strings.Builder
+	lineStart := l.lineStart
+	l.lineStart = false
+	tok.lineStart = lineStart
+
+	switch {
+	case ch == '\n':
+		tok.kind = tokenNewline
+		l.advance(&sb)
+		l.lineStart = true
+	case ch == '\r':
+		// 兼容 Windows 换行
+		l.advance(&sb)
+		if next, err := l.peek(0); err != nil {
+			return token{}, err
+		} else if next == '\n' {
+			l.advance(&sb)
+			tok.kind = tokenNewline
+			l.lineStart = true
+		} else {
+			tok.kind = tokenSpace
+			l.lineStart = lineStart
+		}
+	case isSpace(ch):
+		tok.kind = tokenSpace
+		if err := l.consumeWhile(&sb, isSpace); err != nil {
+			return token{}, err
+		}
+		l.lineStart = lineStart
+	case ch == '-':
+		next, err := l.peek(1)
+		if err != nil {
+			return token{}, err
+		}
+		if next != '-' {
+			tok.kind = tokenSymbol
+			l.advance(&sb)
+			break
+		}
+		tok.kind = tokenLineComment
+		if err := l.consumeWhile(&sb, func(r rune) bool { return r != '\n' && r != '\r' }); err != nil {
+			return token{}, err
+		}
+	case ch == '/':
+		next, err := l.peek(1)
+		if err != nil {
+			return token{}, err
+		}
+		if next == '*' {
+			tok.kind = tokenBlockComment
+			if err := l.lexBlockComment(&sb, tok.line); err != nil {
+				return token{}, err
+			}
+			break
+		}
+		if lineStart {
+			slash, err := l.restIsBlank(1)
+			if err != nil {
+				return token{}, err
+			}
+			if slash {
+				tok.kind = tokenSlash
+				l.advance(&sb)
+				if err := l.consumeWhile(&sb, isSpace); err != nil {
+					return token{}, err
+				}
+				break
+			}
+		}
+		tok.kind = tokenSymbol
+		l.advance(&sb)
+	case ch == ';':
+		tok.kind = tokenSemicolon
+		l.advance(&sb)
+	case ch == '\'':
+		tok.kind = tokenString
+		if err := l.lexQuoted(&sb, '\'', tok.line, "字符串"); err != nil {
+			return token{}, err
+		}
+	case ch == '"':
+		tok.kind = tokenQuotedIdent
+		if err := l.lexQuoted(&sb, '"', tok.line, "引号标识符"); err != nil {
+			return token{}, err
+		}
+	case unicode.IsDigit(ch) || (ch == '.' && l.nextIsDigit()):
+		tok.kind = tokenNumber
+		if err := l.lexNumber(&sb); err != nil {
+			return token{}, err
+		}
+	case ch == '.':
+		// PL/SQL 范围运算符 .. 作为一个整体
+		tok.kind = tokenSymbol
+		l.advance(&sb)
+		if next, err := l.peek(0); err != nil {
+			return token{}, err
+		} else if next == '.' {
+			l.advance(&sb)
+		}
+	case isWordStart(ch):
+		kind, err := l.lexWord(&sb, tok.line)
+		if err != nil {
+			return token{}, err
+		}
+		tok.kind = kind
+	default:
+		tok.kind = tokenSymbol
+		l.advance(&sb)
+	}
+
+	tok.text = sb.String()
+	tok.endLine = l.line
+	if tok.kind == tokenNewline {
+		tok.endLine = tok.line
+	}
+	return tok, nil
+}
+
+// consumeWhile 连续消费满足条件的字符
+func (l *lexer) consumeWhile(sb *strings.Builder, fn func(rune) bool) error {
+	for {
+		ch, err := l.peek(0)
+		if err != nil {
+			return err
+		}
+		if len(l.buf) == 0 || !fn(ch) {
+			return nil
+		}
+		l.advance(sb)
+	}
+}
+
+// restIsBlank 判断从第 i 个预读字符到行尾是否只有空白
+func (l *lexer) restIsBlank(i int) (bool, error) {
+	for ; ; i++ {
+		ch, err := l.peek(i)
+		if err != nil {
+			return false, err
+		}
+		if i >= len(l.buf) || ch == '\n' || ch == '\r' {
+			return true, nil
+		}
+		if !isSpace(ch) {
+			return false, nil
+		}
+	}
+}
+
+// peekLine 返回当前行剩余的内容（不含换行符），不消费字符
+func (l *lexer) peekLine() (string, error) {
+	for i := 0; ; i++ {
+		ch, err := l.peek(i)
+		if err != nil {
+			return "", err
+		}
+		if i >= len(l.buf) || ch == '\n' || ch == '\r' {
+			return string(l.buf[:i]), nil
+		}
+	}
+}
+
+// readLine 读取当前行剩余的内容（不含换行符）
+func (l *lexer) readLine() (string, error) {
+	var sb strings.Builder
+	err := l.consumeWhile(&sb, func(r rune) bool { return r != '\n' && r != '\r' })
+	return sb.String(), err
+}
+
+// skipNewline 消费一个换行符
+func (l *lexer) skipNewline() error {
+	var sb strings.Builder
+	ch, err := l.peek(0)
+	if err != nil {
+		return err
+	}
+	if ch == '\r' {
+		l.advance(&sb)
+		if ch, err = l.peek(0); err != nil {
+			return err
+		}
+	}
+	if ch == '\n' {
+		l.advance(&sb)
+		l.lineStart = true
+	}
+	return nil
+}
+
+// nextIsDigit 判断第二个预读字符是否为数字
+func (l *lexer) nextIsDigit() bool {
+	next, err := l.peek(1)
+	return err == nil && unicode.IsDigit(next)
+}
+
+// lexBlockComment 读取 /* */ 块注释
+func (l *lexer) lexBlockComment(sb *strings.Builder, line int) error {
+	l.advance(sb)
+	l.advance(sb)
+	for {
+		ch, err := l.peek(0)
+		if err != nil {
+			return err
+		}
+		if len(l.buf) == 0 {
+			return fmt.Errorf("第 %d 行: 块注释未闭合", line)
+		}
+		l.advance(sb)
+		if ch == '*' {
+			next, err := l.peek(0)
+			if err != nil {
+				return err
+			}
+			if next == '/' {
+				l.advance(sb)
+				return nil
+			}
+		}
+	}
+}
+
+// lexQuoted 读取以 quote 包围的内容，连续两个 quote 表示转义
+func (l *lexer) lexQuoted(sb *strings.Builder, quote rune, line int, what string) error {
+	l.advance(sb)
+	for {
+		ch, err := l.peek(0)
+		if err != nil {
+			return err
+		}
+		if len(l.buf) == 0 {
+			return fmt.Errorf("第 %d 行: %s未闭合", line, what)
+		}
+		l.advance(sb)
+		if ch != quote {
+			continue
+		}
+		next, err := l.peek(0)
+		if err != nil {
+			return err
+		}
+		if next != quote {
+			return nil
+		}
+		l.advance(sb)
+	}
+}
+
+// lexQQuote 读取 q'X...X' 形式的字符串，调用时预读缓冲以开头的单引号起始
+func (l *lexer) lexQQuote(sb *strings.Builder, line int) error {
+	l.advance(sb) // '
+	open, err := l.peek(0)
+	if err != nil {
+		return err
+	}
+	if len(l.buf) == 0 || isSpace(open) || open == '\n' {
+		return fmt.Errorf("第 %d 行: q 字符串缺少分隔符", line)
+	}
+	l.advance(sb)
+
+	closing := open
+	switch open {
+	case '[':
+		closing = ']'
+	case '{':
+		closing = '}'
+	case '<':
+		closing = '>'
+	case '(':
+		closing = ')'
+	}
+
+	for {
+		ch, err := l.peek(0)
+		if err != nil {
+			return err
+		}
+		if len(l.buf) == 0 {
+			return fmt.Errorf("第 %d 行: q 字符串未闭合", line)
+		}
+		l.advance(sb)
+		if ch != closing {
+			continue
+		}
+		next, err := l.peek(0)
+		if err != nil {
+			return err
+		}
+		if next == '\'' {
+			l.advance(sb)
+			return nil
+		}
+	}
+}
+
+// lexNumber 读取数字字面量
+func (l *lexer) lexNumber(sb *strings.Builder) error {
+	if err := l.consumeWhile(sb, unicode.IsDigit); err != nil {
+		return err
+	}
+	ch, err := l.peek(0)
+	if err != nil {
+		return err
+	}
+	// 1..3 这样的范围运算符不属于数字
+	if ch == '.' && l.nextIsDigit() {
+		l.advance(sb)
+		if err := l.consumeWhile(sb, unicode.IsDigit); err != nil {
+			return err
+		}
+		ch, err = l.peek(0)
+		if err != nil {
+			return err
+		}
+	}
+	if ch == 'e' || ch == 'E' {
+		next, err := l.peek(1)
+		if err != nil {
+			return err
+		}
+		after, err := l.peek(2)
+		if err != nil {
+			return err
+		}
+		if unicode.IsDigit(next) || ((next == '+' || next == '-') && unicode.IsDigit(after)) {
+			l.advance(sb)
+			if next == '+' || next == '-' {
+				l.advance(sb)
+			}
+			return l.consumeWhile(sb, unicode.IsDigit)
+		}
+	}
+	return nil
+}
+
+// lexWord 读取关键字或标识符，并识别 N'...'、q'...'、nq'...' 前缀的字符串
+func (l *lexer) lexWord(sb *strings.Builder, line int) (tokenKind, error) {
+	ch, _ := l.peek(0)
+	next, err := l.peek(1)
+	if err != nil {
+		return tokenEOF, err
+	}
+
+	switch unicode.ToLower(ch) {
+	case 'q':
+		if next == '\'' {
+			l.advance(sb)
+			return tokenString, l.lexQQuote(sb, line)
+		}
+	case 'n', 'u':
+		if next == '\'' {
+			l.advance(sb)
+			return tokenString, l.lexQuoted(sb, '\'', line, "字符串")
+		}
+		after, err := l.peek(2)
+		if err != nil {
+			return tokenEOF, err
+		}
+		if unicode.ToLower(ch) == 'n' && (next == 'q' || next == 'Q') && after == '\'' {
+			l.advance(sb)
+			l.advance(sb)
+			return tokenString, l.lexQQuote(sb, line)
+		}
+	}
+
+	return tokenWord, l.consumeWhile(sb, isWordPart)
+}
+
+func isSpace(r rune) bool {
+	return r != '\n' && r != '\r' && unicode.IsSpace(r)
+}
+
+func isWordStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isWordPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$' || r == '#'
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lexAll 读取全部有效词法单元
+func lexAll(t *testing.T, content string) []token {
+	t.Helper()
+	lex := newLexer(strings.NewReader(content))
+	var tokens []token
+	for {
+		tok, err := lex.next()
+		require.NoError(t, err)
+		if tok.kind == tokenEOF {
+			return tokens
+		}
+		if tok.kind == tokenSpace || tok.kind == tokenNewline {
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		kinds []tokenKind
+		texts []string
+	}{
+		{
+			name:  "字符串中的分号",
+			input: `SELECT 'a;b' FROM dual;`,
+			kinds: []tokenKind{tokenWord, tokenString, tokenWord, tokenWord, tokenSemicolon},
+			texts: []string{"SELECT", "'a;b'", "FROM", "dual", ";"},
+		},
+		{
+			name:  "转义单引号",
+			input: `'it''s'`,
+			kinds: []tokenKind{tokenString},
+			texts: []string{"'it''s'"},
+		},
+		{
+			name:  "q字符串",
+			input: `q'[it's; ] done]' nq'{x}'`,
+			kinds: []tokenKind{tokenString, tokenString},
+			texts: []string{"q'[it's; ] done]'", "nq'{x}'"},
+		},
+		{
+			name:  "国家字符集字符串",
+			input: `N'中文;'`,
+			kinds: []tokenKind{tokenString},
+			texts: []string{"N'中文;'"},
+		},
+		{
+			name:  "引号标识符",
+			input: `"my;col" "a""b"`,
+			kinds: []tokenKind{tokenQuotedIdent, tokenQuotedIdent},
+			texts: []string{`"my;col"`, `"a""b"`},
+		},
+		{
+			name:  "注释",
+			input: "-- line; comment\n/* block;\n comment */ x",
+			kinds: []tokenKind{tokenLineComment, tokenBlockComment, tokenWord},
+			texts: []string{"-- line; comment", "/* block;\n comment */", "x"},
+		},
+		{
+			name:  "斜杠结束符",
+			input: "END;\n  /  \nSELECT 4 / 2 FROM dual",
+			kinds: []tokenKind{tokenWord, tokenSemicolon, tokenSlash, tokenWord, tokenNumber, tokenSymbol, tokenNumber, tokenWord, tokenWord},
+		},
+		{
+			name:  "行首除号不是结束符",
+			input: "SELECT a\n/ b",
+			kinds: []tokenKind{tokenWord, tokenWord, tokenSymbol, tokenWord},
+		},
+		{
+			name:  "数字与范围运算符",
+			input: "1..3 1.5e-3 .5",
+			kinds: []tokenKind{tokenNumber, tokenSymbol, tokenNumber, tokenNumber, tokenNumber},
+			texts: []string{"1", "..", "3", "1.5e-3", ".5"},
+		},
+		{
+			name:  "标识符",
+			input: "v$session sys_c0001 emp#",
+			kinds: []tokenKind{tokenWord, tokenWord, tokenWord},
+			texts: []string{"v$session", "sys_c0001", "emp#"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := lexAll(t, tt.input)
+			kinds := make([]tokenKind, len(tokens))
+			texts := make([]string, len(tokens))
+			for i, tok := range tokens {
+				kinds[i] = tok.kind
+				texts[i] = tok.text
+			}
+			assert.Equal(t, tt.kinds, kinds)
+			if tt.texts != nil {
+				assert.Equal(t, tt.texts, texts)
+			}
+		})
+	}
+}
+
+func TestLexerPosition(t *testing.T) {
+	tokens := lexAll(t, "SELECT\n  'a\nb' x\r\nFROM dual")
+	require.Len(t, tokens, 5)
+
+	assert.Equal(t, 1, tokens[0].line)
+	assert.Equal(t, 2, tokens[1].line)
+	assert.Equal(t, 3, tokens[1].col)
+	assert.Equal(t, 3, tokens[1].endLine)
+	assert.Equal(t, 3, tokens[2].line)
+	assert.Equal(t, 4, tokens[3].line)
+	assert.Equal(t, 1, tokens[3].col)
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "字符串未闭合", input: "SELECT\n'abc", want: "第 2 行: 字符串未闭合"},
+		{name: "块注释未闭合", input: "/* abc", want: "第 1 行: 块注释未闭合"},
+		{name: "引号标识符未闭合", input: `"abc`, want: "引号标识符未闭合"},
+		{name: "q字符串未闭合", input: "q'[abc]", want: "q 字符串未闭合"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lex := newLexer(strings.NewReader(tt.input))
+			var err error
+			for err == nil {
+				var tok token
+				tok, err = lex.next()
+				if tok.kind == tokenEOF && err == nil {
+					break
+				}
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
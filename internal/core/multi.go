@@ -0,0 +1,136 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iyuangang/oracle-sql-runner/internal/config"
+	"github.com/iyuangang/oracle-sql-runner/internal/utils"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// ExecuteDatabases 在多个数据库上执行同一脚本，返回汇总结果
+//
+// 最多同时在 cfg.DBConcurrency 个数据库上执行，各数据库的输出按 names 的顺序
+// 依次写入 out，不会相互穿插。查询结果文件写入结果文件目录下以数据库名称
+// 命名的子目录，并发执行的数据库不会相互覆盖。
+func ExecuteDatabases(cfg *config.Config, names []string, script []byte, name string, logger *utils.Logger, out io.Writer) *models.Report {
+	return ExecuteWaves(cfg, [][]string{names}, script, name, logger, out, nil)
+}
+
+// WaveGate 在第 wave 批（从 0 开始）开始前调用，返回 false 时停止执行
+type WaveGate func(wave int, names []string) bool
+
+// ExecuteWaves 按批次依次在多个数据库上执行同一脚本，返回汇总结果
+//
+// 每批内的数据库按 ExecuteDatabases 的方式并发执行，一批全部完成后才开始下一批。
+// 某一批中有数据库执行失败时不再执行之后的批次；gate 不为 nil 时在第二批起的每批
+// 开始前调用，用于等待或确认，返回 false 时同样停止。未执行的数据库记录在汇总结果
+// 的 Pending 中。
+func ExecuteWaves(cfg *config.Config, waves [][]string, script []byte, name string, logger *utils.Logger, out io.Writer, gate WaveGate) *models.Report {
+	run := func(dbName string, output io.Writer) *models.Result {
+		return executeDatabase(cfg, dbName, script, name, logger, output)
+	}
+	return executeWaves(cfg.DBConcurrency, waves, run, logger, out, gate)
+}
+
+// databaseRunner 在单个数据库上执行脚本，输出写入 output
+type databaseRunner func(dbName string, output io.Writer) *models.Result
+
+// executeWaves 按批次依次调用 run 执行各数据库，每批最多同时执行 concurrency 个
+func executeWaves(concurrency int, waves [][]string, run databaseRunner, logger *utils.Logger, out io.Writer, gate WaveGate) *models.Report {
+	start := time.Now()
+	report := &models.Report{}
+
+	for i, names := range waves {
+		if i > 0 {
+			if failed := report.Failed(); len(failed) > 0 {
+				logger.Warn("数据库执行失败，停止执行之后的批次", "wave", i, "failed", failed)
+				fmt.Fprintf(out, "第 %d 批中 %s 执行失败，停止执行之后的批次\n", i, strings.Join(failed, ", "))
+				report.Pending = pendingDatabases(waves[i:])
+				break
+			}
+			if gate != nil && !gate(i, names) {
+				logger.Warn("已取消之后的批次", "wave", i+1)
+				fmt.Fprintf(out, "已取消第 %d 批及之后的批次\n", i+1)
+				report.Pending = pendingDatabases(waves[i:])
+				break
+			}
+		}
+		if len(waves) > 1 {
+			logger.Info("开始执行批次", "wave", i+1, "databases", names)
+			fmt.Fprintf(out, "##### 第 %d/%d 批: %s #####\n\n", i+1, len(waves), strings.Join(names, ", "))
+		}
+		report.Databases = append(report.Databases, executeDatabases(concurrency, names, run, out)...)
+	}
+
+	report.Duration = time.Since(start)
+	return report
+}
+
+// pendingDatabases 返回各批次中的全部数据库
+func pendingDatabases(waves [][]string) []string {
+	var names []string
+	for _, wave := range waves {
+		names = append(names, wave...)
+	}
+	return names
+}
+
+// executeDatabases 在一批数据库上并发执行脚本，结果按 names 的顺序返回
+func executeDatabases(concurrency int, names []string, run databaseRunner, out io.Writer) []models.DatabaseResult {
+	results := make([]models.DatabaseResult, len(names))
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if concurrency > len(names) {
+		concurrency = len(names)
+	}
+
+	sched := newScheduler(len(names), nil, outputWindow*concurrency)
+	stream := newOrderedOutput(out, len(names))
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i, ok := sched.take()
+				if !ok {
+					return
+				}
+				output := stream.task(i)
+				fmt.Fprintf(output, "=== 数据库 %s ===\n", names[i])
+				result := run(names[i], output)
+				result.Fprint(output)
+				fmt.Fprintln(output)
+				results[i] = models.DatabaseResult{Database: names[i], Result: result}
+				output.close()
+				sched.done(i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// executeDatabase 在单个数据库上执行脚本，输出写入 output
+func executeDatabase(cfg *config.Config, dbName string, script []byte, name string, logger *utils.Logger, output io.Writer) *models.Result {
+	executor, err := NewExecutor(cfg, dbName, logger)
+	if err != nil {
+		logger.Error("创建执行器失败", "database", dbName, "error", err)
+		return models.NewErrorResult(fmt.Errorf("创建执行器失败: %w", err))
+	}
+	defer executor.Close()
+
+	executor.SetOutput(output)
+	executor.spoolSubdir = dbName
+	return executor.ExecuteReader(bytes.NewReader(script), name)
+}
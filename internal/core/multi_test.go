@@ -0,0 +1,122 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/internal/config"
+	"github.com/iyuangang/oracle-sql-runner/internal/utils"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteDatabasesExecutorError(t *testing.T) {
+	logger, err := utils.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug", true)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	// 未配置的数据库无法创建执行器，不需要连接数据库
+	cfg := &config.Config{DBConcurrency: 2, Databases: map[string]config.DatabaseConfig{}}
+	names := []string{"db1", "db2", "db3"}
+
+	var out bytes.Buffer
+	report := ExecuteDatabases(cfg, names, []byte("SELECT 1 FROM dual;\n"), "deploy.sql", logger, &out)
+
+	require.Len(t, report.Databases, len(names))
+	for i, d := range report.Databases {
+		assert.Equal(t, names[i], d.Database)
+		assert.False(t, d.Succeeded())
+		require.NotEmpty(t, d.Result.Errors)
+		assert.Contains(t, d.Result.Errors[0].Message, "未找到数据库配置: "+names[i])
+	}
+	assert.Equal(t, 1, report.ExitCode())
+	assert.Equal(t, names, report.Failed())
+
+	// 各数据库的输出按顺序依次写出
+	output := out.String()
+	last := -1
+	for _, name := range names {
+		i := strings.Index(output, "=== 数据库 "+name+" ===")
+		require.GreaterOrEqual(t, i, 0, name)
+		assert.Greater(t, i, last, name)
+		last = i
+	}
+}
+
+func TestExecuteWaves(t *testing.T) {
+	logger, err := utils.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug", true)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	cfg := &config.Config{DBConcurrency: 2, Databases: map[string]config.DatabaseConfig{}}
+	script := []byte("SELECT 1 FROM dual;\n")
+
+	t.Run("失败后停止", func(t *testing.T) {
+		gateCalls := 0
+		gate := func(int, []string) bool {
+			gateCalls++
+			return true
+		}
+		var out bytes.Buffer
+		waves := [][]string{{"canary"}, {"db1", "db2"}, {"db3"}}
+		report := ExecuteWaves(cfg, waves, script, "deploy.sql", logger, &out, gate)
+
+		require.Len(t, report.Databases, 1)
+		assert.Equal(t, "canary", report.Databases[0].Database)
+		assert.Equal(t, []string{"db1", "db2", "db3"}, report.Pending)
+		assert.Equal(t, 0, gateCalls, "失败后不再等待确认")
+		assert.Equal(t, 1, report.ExitCode())
+		assert.Contains(t, out.String(), "##### 第 1/3 批: canary #####")
+		assert.Contains(t, out.String(), "第 1 批中 canary 执行失败，停止执行之后的批次")
+		assert.NotContains(t, out.String(), "=== 数据库 db1 ===")
+	})
+
+	t.Run("取消", func(t *testing.T) {
+		var gateWaves []int
+		gate := func(wave int, names []string) bool {
+			gateWaves = append(gateWaves, wave)
+			return false
+		}
+		var out bytes.Buffer
+		waves := [][]string{{}, {"db1"}, {"db2"}}
+		report := ExecuteWaves(cfg, waves, script, "deploy.sql", logger, &out, gate)
+
+		assert.Empty(t, report.Databases)
+		assert.Equal(t, []string{"db1", "db2"}, report.Pending)
+		assert.Equal(t, []int{1}, gateWaves)
+		assert.Equal(t, 1, report.ExitCode())
+		assert.Contains(t, out.String(), "已取消第 2 批及之后的批次")
+	})
+}
+
+func TestExecuteWavesExitAfterFailure(t *testing.T) {
+	e := newCommandExecutor(t)
+
+	// 默认 WHENEVER OSERROR CONTINUE，SPOOL 失败后脚本仍以 EXIT 0 结束
+	script := "SPOOL " + filepath.Join(t.TempDir(), "missing", "out.lst") + "\nPROMPT done\nEXIT\n"
+	var runs []string
+	run := func(dbName string, output io.Writer) *models.Result {
+		runs = append(runs, dbName)
+		e.SetOutput(output)
+		parser := ParseReader(strings.NewReader(script), ParseOptions{Filename: "deploy.sql"})
+		defer parser.Close()
+		return e.executeScript(parser)
+	}
+
+	var out bytes.Buffer
+	report := executeWaves(1, [][]string{{"canary"}, {"db1"}}, run, e.logger, &out, nil)
+
+	require.Len(t, report.Databases, 1)
+	result := report.Databases[0].Result
+	require.True(t, result.Exited)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, 1, result.Failed)
+	assert.False(t, report.Databases[0].Succeeded())
+	assert.Equal(t, []string{"canary"}, runs, "金丝雀数据库失败后不执行下一批")
+	assert.Equal(t, []string{"db1"}, report.Pending)
+	assert.Equal(t, 1, report.ExitCode())
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// orderedOutput 按脚本顺序输出并行任务的内容
+//
+// 最早尚未完成的任务直接写入控制台，其他任务的输出先缓存，等之前的任务全部完成后
+// 再依次写出。缓存的任务数由调度器的窗口限制，每个任务的缓存超过 limit 字节后
+// 写入方等待，直到该任务成为最早未完成的任务。
+type orderedOutput struct {
+	mu    sync.Mutex
+	cond  *sync.Cond // 最早未完成的任务变化时通知等待的写入方
+	w     io.Writer
+	tasks []*outputCapture
+	head  int // 最早尚未完成的任务下标
+	limit int // 每个任务最多缓存的字节数
+	err   error
+}
+
+// outputBufferLimit 每个任务最多缓存的输出字节数
+const outputBufferLimit = 1 << 20
+
+// newOrderedOutput 为 count 个任务创建有序输出
+func newOrderedOutput(w io.Writer, count int) *orderedOutput {
+	o := &orderedOutput{w: w, tasks: make([]*outputCapture, count), limit: outputBufferLimit}
+	o.cond = sync.NewCond(&o.mu)
+	for i := range o.tasks {
+		o.tasks[i] = &outputCapture{stream: o, index: i}
+	}
+	return o
+}
+
+// task 返回第 i 个任务的输出
+func (o *orderedOutput) task(i int) *outputCapture {
+	return o.tasks[i]
+}
+
+// write 写入控制台，调用方需持有锁，出错后丢弃之后的输出
+func (o *orderedOutput) write(p []byte) {
+	if o.err != nil {
+		return
+	}
+	_, o.err = o.w.Write(p)
+}
+
+// Err 返回写入控制台时遇到的第一个错误
+func (o *orderedOutput) Err() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+// outputCapture 单个任务的输出
+//
+// 不属于有序输出时只缓存内容，用于单独执行任务的场景。
+type outputCapture struct {
+	stream *orderedOutput
+	index  int
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (c *outputCapture) Write(p []byte) (int, error) {
+	if c.stream == nil {
+		return c.buf.Write(p)
+	}
+	c.stream.mu.Lock()
+	defer c.stream.mu.Unlock()
+	// 缓存已满时等待之前的任务完成，避免大量输出全部留在内存中
+	for c.index != c.stream.head && c.buf.Len() >= c.stream.limit {
+		c.stream.cond.Wait()
+	}
+	if c.index == c.stream.head {
+		c.stream.write(p)
+	} else {
+		c.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// close 标记任务完成，任务成为最早未完成的任务时写出之后已完成任务的缓存，
+// 并将下一个仍在执行的任务切换为直接输出
+func (c *outputCapture) close() {
+	o := c.stream
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	c.closed = true
+	head := o.head
+	for o.head < len(o.tasks) && o.tasks[o.head].closed {
+		o.head++
+		if o.head < len(o.tasks) {
+			next := o.tasks[o.head]
+			o.write(next.buf.Bytes())
+			next.buf = bytes.Buffer{}
+		}
+	}
+	if o.head != head {
+		o.cond.Broadcast()
+	}
+}
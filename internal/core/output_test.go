@@ -0,0 +1,96 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedOutput(t *testing.T) {
+	var console bytes.Buffer
+	stream := newOrderedOutput(&console, 4)
+
+	fmt.Fprint(stream.task(2), "c1 ")
+	fmt.Fprint(stream.task(0), "a1 ")
+	assert.Equal(t, "a1 ", console.String(), "最早的任务直接输出")
+
+	fmt.Fprint(stream.task(1), "b1 ")
+	stream.task(2).close()
+	assert.Equal(t, "a1 ", console.String(), "之前的任务未完成时缓存")
+
+	fmt.Fprint(stream.task(0), "a2 ")
+	stream.task(0).close()
+	assert.Equal(t, "a1 a2 b1 ", console.String(), "切换到下一个任务时写出其缓存")
+
+	fmt.Fprint(stream.task(1), "b2 ")
+	assert.Equal(t, "a1 a2 b1 b2 ", console.String())
+
+	stream.task(1).close()
+	assert.Equal(t, "a1 a2 b1 b2 c1 ", console.String(), "写出之后已完成任务的缓存")
+
+	fmt.Fprint(stream.task(3), "d1")
+	stream.task(3).close()
+	assert.Equal(t, "a1 a2 b1 b2 c1 d1", console.String())
+	assert.NoError(t, stream.Err())
+}
+
+func TestOrderedOutputConcurrent(t *testing.T) {
+	const count = 50
+	var console bytes.Buffer
+	stream := newOrderedOutput(&console, count)
+
+	var wg sync.WaitGroup
+	for i := count - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			out := stream.task(i)
+			for j := 0; j < 3; j++ {
+				fmt.Fprintf(out, "%d.%d\n", i, j)
+			}
+			out.close()
+		}(i)
+	}
+	wg.Wait()
+
+	var want bytes.Buffer
+	for i := 0; i < count; i++ {
+		for j := 0; j < 3; j++ {
+			fmt.Fprintf(&want, "%d.%d\n", i, j)
+		}
+	}
+	assert.Equal(t, want.String(), console.String())
+}
+
+func TestOrderedOutputLimit(t *testing.T) {
+	var console bytes.Buffer
+	stream := newOrderedOutput(&console, 2)
+	stream.limit = 4
+
+	fmt.Fprint(stream.task(0), "a ")
+	fmt.Fprint(stream.task(1), "bbbb")
+
+	written := make(chan struct{})
+	go func() {
+		fmt.Fprint(stream.task(1), " b2")
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("缓存已满时写入应等待之前的任务完成")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, "a ", console.String())
+
+	stream.task(0).close()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("成为最早未完成的任务后写入应继续")
+	}
+	assert.Equal(t, "a bbbb b2", console.String())
+}
@@ -1,17 +1,20 @@
 package core
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/iyuangang/oracle-sql-runner/pkg/models"
 )
 
 // normalizeSQL 规范化SQL语句格式
 func normalizeSQL(sql string, sqlType models.SQLType) string {
-	if sqlType != models.SQLTypePLSQL {
+	if !sqlType.IsPLSQL() {
 		return strings.TrimSpace(sql)
 	}
 
@@ -66,148 +69,510 @@ func normalizeSQL(sql string, sqlType models.SQLType) string {
 	return strings.Join(normalized, "\n")
 }
 
-// validateSQLContent 验证SQL文件内容是否有效
-func validateSQLContent(content string) error {
-	// 去除注释和空行
-	lines := strings.Split(content, "\n")
-	var validLines []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "--") {
-			continue
+// isPLSQLStart 根据语句开头判断是否为 PL/SQL 程序单元或匿名块
+func isPLSQLStart(lead []token) bool {
+	return classifyTokens(lead).sqlType.IsPLSQL()
+}
+
+// maxLeadTokens 用于判断语句类型所保留的开头词法单元数量
+const maxLeadTokens = 16
+
+// scriptParser 基于词法分析的SQL脚本解析器，逐条返回语句
+type scriptParser struct {
+	lex      *lexer
+	filename string
+	path     string    // 当前脚本的绝对路径，用于检测循环引用
+	closer   io.Closer // 当前脚本由 @/@@ 打开时需要关闭
+	stack    []includeFrame
+	subst    *substitution
+	pending  models.Directives // 尚未应用到语句的指令
+	count    int               // 已生成的SQL语句数，包含子脚本中的语句
+	names    map[string]string // @runner:name 指定的语句名称及其所在位置
+}
+
+// includeFrame 执行 @/@@ 时保存的上层脚本状态
+type includeFrame struct {
+	lex      *lexer
+	filename string
+	path     string
+	closer   io.Closer
+}
+
+// newScriptParser 创建脚本解析器
+func newScriptParser(r io.Reader, opts ParseOptions) *scriptParser {
+	path, err := filepath.Abs(opts.Filename)
+	if err != nil {
+		path = opts.Filename
+	}
+	return &scriptParser{
+		lex:      newLexer(r),
+		filename: opts.Filename,
+		path:     path,
+		subst:    newSubstitution(opts.Defines),
+		names:    make(map[string]string),
+	}
+}
+
+// statement 正在收集中的语句
+type statement struct {
+	tokens  []token
+	lead    []token
+	endLine int
+}
+
+func (s *statement) started() bool {
+	return len(s.lead) > 0
+}
+
+func (s *statement) add(tok token) {
+	if tok.significant() {
+		if len(s.lead) < maxLeadTokens {
+			s.lead = append(s.lead, tok)
 		}
-		validLines = append(validLines, line)
+		s.endLine = tok.endLine
 	}
+	s.tokens = append(s.tokens, tok)
+}
 
-	// 如果去除注释和空行后没有内容，则无效
-	if len(validLines) == 0 {
-		return fmt.Errorf("SQL文件内容为空或仅包含注释")
+// next 返回下一条语句，脚本结束时返回 nil
+func (p *scriptParser) next() (*models.SQLTask, error) {
+	var stmt statement
+
+	for {
+		tok, err := p.lex.next()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.filename, err)
+		}
+
+		switch tok.kind {
+		case tokenEOF:
+			if !stmt.started() {
+				if len(p.stack) == 0 {
+					return nil, nil
+				}
+				if err := p.pop(); err != nil {
+					return nil, fmt.Errorf("%s: %w", p.filename, err)
+				}
+				continue
+			}
+			return p.build(&stmt, stmt.endLine)
+		case tokenSlash:
+			if !stmt.started() {
+				continue
+			}
+			return p.build(&stmt, tok.line)
+		case tokenSemicolon:
+			if !stmt.started() {
+				continue
+			}
+			if !isPLSQLStart(stmt.lead) {
+				return p.build(&stmt, tok.line)
+			}
+			stmt.add(tok)
+		default:
+			// 语句开始前的空白和注释不属于语句内容，独占一行的指令注释作用于下一条语句
+			if !stmt.started() && !tok.significant() {
+				if tok.kind == tokenLineComment && tok.lineStart {
+					if _, err := parseDirective(tok.text, &p.pending); err != nil {
+						return nil, fmt.Errorf("%s: 第 %d 行: %w", p.filename, tok.line, err)
+					}
+				}
+				continue
+			}
+			if !stmt.started() && tok.kind == tokenSymbol && tok.text == "@" && tok.lineStart {
+				p.pending = models.Directives{}
+				if err := p.include(tok); err != nil {
+					return nil, fmt.Errorf("%s: %w", p.filename, err)
+				}
+				continue
+			}
+			if !stmt.started() && tok.kind == tokenWord && tok.lineStart {
+				task, ok, err := p.command(tok)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", p.filename, err)
+				}
+				if ok {
+					if task == nil {
+						continue
+					}
+					return task, nil
+				}
+			}
+			stmt.add(tok)
+		}
 	}
+}
 
-	// 检查是否包含常见SQL关键字
-	content = strings.ToUpper(strings.Join(validLines, " "))
-	validKeywords := []string{
-		"SELECT", "INSERT", "UPDATE", "DELETE",
-		"CREATE", "ALTER", "DROP", "MERGE",
-		"BEGIN", "DECLARE", "EXECUTE", "GRANT",
-		"TRUNCATE", "COMMENT", "ANALYZE", "CALL",
+// command 识别以 tok 开头的 SQL*Plus 命令，命令占据整行，行尾的 " -" 表示续行
+func (p *scriptParser) command(tok token) (*models.SQLTask, bool, error) {
+	name := matchCommand(tok.text)
+	if name == "" {
+		return nil, false, nil
 	}
 
-	for _, keyword := range validKeywords {
-		if strings.Contains(content, keyword) {
-			return nil
+	rest, err := p.lex.peekLine()
+	if err != nil {
+		return nil, false, err
+	}
+	if name == "SET" && isSQLSet(rest) {
+		return nil, false, nil
+	}
+
+	text := tok.text
+	raw := tok.text
+	lineNum := tok.line
+	for {
+		line, err := p.lex.readLine()
+		if err != nil {
+			return nil, false, err
+		}
+		raw += line
+		trimmed := strings.TrimRightFunc(line, unicode.IsSpace)
+		if !strings.HasSuffix(trimmed, " -") && !strings.HasSuffix(trimmed, "\t-") {
+			text += line
+			break
+		}
+		raw += "\n"
+		text += strings.TrimSuffix(trimmed, "-")
+		if err := p.lex.skipNewline(); err != nil {
+			return nil, false, err
 		}
+		lineNum++
 	}
 
-	return fmt.Errorf("SQL文件内容无效: 未找到有效的SQL语句")
+	if name == "REMARK" {
+		return nil, true, nil
+	}
+	// 指令只作用于SQL语句
+	p.pending = models.Directives{}
+
+	text, err = p.subst.apply(strings.TrimSpace(text), tok.line)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := p.define(text); err != nil {
+		return nil, false, fmt.Errorf("第 %d 行: %w", tok.line, err)
+	}
+
+	return &models.SQLTask{
+		SQL:       text,
+		Type:      models.SQLTypeCommand,
+		Verb:      name,
+		LineNum:   lineNum,
+		StartLine: tok.line,
+		EndLine:   lineNum,
+		Text:      strings.TrimRightFunc(raw, unicode.IsSpace),
+		Filename:  p.filename,
+	}, true, nil
 }
 
-// ParseFile 解析SQL文件
-func ParseFile(path string) ([]models.SQLTask, error) {
-	content, err := os.ReadFile(path)
+// include 处理 @file 与 @@file，@ 相对于当前工作目录，@@ 相对于当前脚本所在目录
+func (p *scriptParser) include(tok token) error {
+	line, err := p.lex.readLine()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	line, err = p.subst.apply(strings.TrimSpace(line), tok.line)
+	if err != nil {
+		return err
 	}
 
-	// 验证SQL文件内容
-	if err := validateSQLContent(string(content)); err != nil {
-		return nil, err
+	relative := strings.HasPrefix(line, "@")
+	fields := strings.Fields(strings.TrimPrefix(line, "@"))
+	if len(fields) == 0 {
+		return fmt.Errorf("第 %d 行: 缺少脚本路径", tok.line)
 	}
 
-	file, err := os.Open(path)
+	filename := fields[0]
+	if filepath.Ext(filename) == "" {
+		filename += ".sql"
+	}
+	if relative && !filepath.IsAbs(filename) {
+		filename = filepath.Join(filepath.Dir(p.filename), filename)
+	}
+	path, err := filepath.Abs(filename)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("第 %d 行: %w", tok.line, err)
 	}
-	defer file.Close()
 
-	var tasks []models.SQLTask
-	scanner := bufio.NewScanner(file)
-	var sqlBuffer strings.Builder
-	lineNum := 0
-	inPLSQLBlock := false
-	hasContent := false
+	chain := make([]string, 0, len(p.stack)+2)
+	for _, frame := range p.stack {
+		chain = append(chain, frame.filename)
+	}
+	chain = append(chain, p.filename, filename)
+	if path == p.path {
+		return fmt.Errorf("第 %d 行: 检测到循环引用: %s", tok.line, strings.Join(chain, " -> "))
+	}
+	for _, frame := range p.stack {
+		if frame.path == path {
+			return fmt.Errorf("第 %d 行: 检测到循环引用: %s", tok.line, strings.Join(chain, " -> "))
+		}
+	}
 
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("第 %d 行: 打开脚本失败: %w", tok.line, err)
+	}
 
-		// 跳过空行和注释
-		if line == "" || strings.HasPrefix(line, "--") {
-			continue
+	// 脚本参数通过 &1、&2 ... 引用
+	for i, arg := range fields[1:] {
+		p.subst.define(strconv.Itoa(i+1), unquoteDefine(arg))
+	}
+
+	p.stack = append(p.stack, includeFrame{lex: p.lex, filename: p.filename, path: p.path, closer: p.closer})
+	p.lex = newLexer(file)
+	p.filename = filename
+	p.path = path
+	p.closer = file
+	return nil
+}
+
+// pop 当前脚本结束，返回上层脚本
+func (p *scriptParser) pop() error {
+	err := p.closer.Close()
+	frame := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	p.lex, p.filename, p.path, p.closer = frame.lex, frame.filename, frame.path, frame.closer
+	// 子脚本末尾的指令不作用于上层脚本
+	p.pending = models.Directives{}
+	return err
+}
+
+// close 关闭所有由 @/@@ 打开的脚本
+func (p *scriptParser) close() {
+	for len(p.stack) > 0 {
+		p.pop()
+	}
+}
+
+// define 在解析阶段处理 DEFINE、UNDEFINE 和 SET DEFINE，使其对后续语句的替换生效
+func (p *scriptParser) define(text string) error {
+	name, args := splitCommand(text)
+	switch name {
+	case "DEFINE":
+		if name, value, ok := parseDefine(args); ok && name != "" {
+			p.subst.define(name, value)
+		}
+	case "UNDEFINE":
+		for _, name := range strings.Fields(args) {
+			p.subst.undefine(name)
+		}
+	case "SET":
+		fields := strings.Fields(args)
+		if len(fields) == 2 && isDefineOption(fields[0]) {
+			return p.subst.setDefine(fields[1])
+		}
+	}
+	return nil
+}
+
+// substitute 替换语句中的变量，注释内容保持不变
+func (p *scriptParser) substitute(tokens []token) (string, error) {
+	var sb, run strings.Builder
+	runLine := 0
+	flush := func() error {
+		text, err := p.subst.apply(run.String(), runLine)
+		if err != nil {
+			return err
 		}
+		sb.WriteString(text)
+		run.Reset()
+		return nil
+	}
 
-		hasContent = true
-
-		// 检查是否进入PL/SQL块
-		upperLine := strings.ToUpper(line)
-		if strings.HasPrefix(upperLine, "BEGIN") ||
-			strings.HasPrefix(upperLine, "DECLARE") ||
-			strings.HasPrefix(upperLine, "CREATE OR REPLACE PROCEDURE") ||
-			strings.HasPrefix(upperLine, "CREATE PROCEDURE") ||
-			strings.HasPrefix(upperLine, "CREATE OR REPLACE FUNCTION") ||
-			strings.HasPrefix(upperLine, "CREATE FUNCTION") ||
-			strings.HasPrefix(upperLine, "CREATE OR REPLACE TRIGGER") ||
-			strings.HasPrefix(upperLine, "CREATE TRIGGER") ||
-			strings.HasPrefix(upperLine, "CREATE OR REPLACE PACKAGE") ||
-			strings.HasPrefix(upperLine, "CREATE PACKAGE") {
-			inPLSQLBlock = true
-		}
-
-		sqlBuffer.WriteString(line)
-		sqlBuffer.WriteString("\n")
-
-		// 处理PL/SQL块
-		if inPLSQLBlock && line == "/" {
-			sql := strings.TrimSpace(sqlBuffer.String())
-			sql = strings.TrimSuffix(sql, "/")
-			tasks = append(tasks, models.SQLTask{
-				SQL:      normalizeSQL(sql, models.SQLTypePLSQL),
-				Type:     models.SQLTypePLSQL,
-				LineNum:  lineNum,
-				Filename: path,
-			})
-			sqlBuffer.Reset()
-			inPLSQLBlock = false
-			hasContent = false
+	for _, tok := range tokens {
+		if tok.kind == tokenLineComment || tok.kind == tokenBlockComment {
+			if err := flush(); err != nil {
+				return "", err
+			}
+			sb.WriteString(tok.text)
 			continue
 		}
+		if run.Len() == 0 {
+			runLine = tok.line
+		}
+		run.WriteString(tok.text)
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
 
-		// 处理普通SQL语句
-		if !inPLSQLBlock && strings.HasSuffix(line, ";") {
-			sql := strings.TrimSpace(sqlBuffer.String())
-			sql = strings.TrimSuffix(sql, ";")
+// build 根据收集的内容生成SQL任务
+func (p *scriptParser) build(stmt *statement, lineNum int) (*models.SQLTask, error) {
+	text, err := p.substitute(stmt.tokens)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.filename, err)
+	}
 
-			sqlType := models.SQLTypeExec
-			if strings.HasPrefix(strings.ToUpper(sql), "SELECT") {
-				sqlType = models.SQLTypeQuery
-			}
+	var raw strings.Builder
+	for _, tok := range stmt.tokens {
+		raw.WriteString(tok.text)
+	}
 
-			tasks = append(tasks, models.SQLTask{
-				SQL:      normalizeSQL(sql, sqlType),
-				Type:     sqlType,
-				LineNum:  lineNum,
-				Filename: path,
-			})
-			sqlBuffer.Reset()
-			hasContent = false
+	info := classify(text)
+	directives := p.pending
+	p.pending = models.Directives{}
+	if name := directives.Name; name != "" {
+		if prev, ok := p.names[name]; ok {
+			return nil, fmt.Errorf("%s: 第 %d 行: 语句名称 %s 已在 %s 使用", p.filename, stmt.tokens[0].line, name, prev)
 		}
+		p.names[name] = fmt.Sprintf("%s 第 %d 行", p.filename, stmt.tokens[0].line)
+	}
+	p.count++
+	return &models.SQLTask{
+		Index:      p.count,
+		SQL:        normalizeSQL(text, info.sqlType),
+		Type:       info.sqlType,
+		Verb:       info.verb,
+		Object:     info.object,
+		LineNum:    lineNum,
+		StartLine:  stmt.tokens[0].line,
+		EndLine:    stmt.endLine,
+		Text:       strings.TrimRightFunc(raw.String(), unicode.IsSpace),
+		Filename:   p.filename,
+		Directives: directives,
+	}, nil
+}
+
+// validKeywords 有效SQL语句的起始关键字
+var validKeywords = map[string]bool{
+	"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "MERGE": true,
+	"BEGIN": true, "DECLARE": true, "EXECUTE": true, "GRANT": true,
+	"TRUNCATE": true, "COMMENT": true, "ANALYZE": true, "CALL": true,
+}
+
+// isValidTask 判断任务是否为命令或能够识别的SQL语句
+func isValidTask(task models.SQLTask) bool {
+	if task.Type != models.SQLTypeExec {
+		return true
+	}
+	word, _, _ := strings.Cut(task.Verb, " ")
+	return validKeywords[word]
+}
+
+// validateTasks 验证解析结果是否包含有效的SQL语句
+func validateTasks(tasks []models.SQLTask) error {
+	if len(tasks) == 0 {
+		return fmt.Errorf("SQL文件内容为空或仅包含注释")
 	}
 
-	// 处理文件末尾的最后一条语句（如果没有分号）
-	if hasContent {
-		sql := strings.TrimSpace(sqlBuffer.String())
-		sqlType := models.SQLTypeExec
-		if strings.HasPrefix(strings.ToUpper(sql), "SELECT") {
-			sqlType = models.SQLTypeQuery
+	for _, task := range tasks {
+		if isValidTask(task) {
+			return nil
 		}
+	}
 
-		tasks = append(tasks, models.SQLTask{
-			SQL:      normalizeSQL(sql, sqlType),
-			Type:     sqlType,
-			LineNum:  lineNum,
-			Filename: path,
-		})
+	return fmt.Errorf("SQL文件内容无效: 未找到有效的SQL语句")
+}
+
+// ParseOptions 脚本解析选项
+type ParseOptions struct {
+	// Filename 脚本文件名，用于错误信息以及解析 @@ 的相对路径
+	Filename string
+	// Defines 替换变量初始值，脚本中的 DEFINE 可覆盖
+	Defines map[string]string
+}
+
+// Parser 流式SQL脚本解析器，边读取边返回语句，不在内存中保留整个脚本
+//
+// 用法与 bufio.Scanner 类似：
+//
+//	parser := ParseReader(r, opts)
+//	defer parser.Close()
+//	for parser.Next() {
+//		task := parser.Task()
+//	}
+//	if err := parser.Err(); err != nil {
+//	}
+type Parser struct {
+	sp      *scriptParser
+	task    models.SQLTask
+	pending []models.SQLTask // 尚未确认脚本有效前缓存的语句
+	valid   bool
+	err     error
+}
+
+// ParseReader 从 r 中流式解析脚本
+func ParseReader(r io.Reader, opts ParseOptions) *Parser {
+	return &Parser{sp: newScriptParser(r, opts)}
+}
+
+// Next 读取下一条语句，脚本结束或出错时返回 false
+func (p *Parser) Next() bool {
+	if len(p.pending) == 0 && !p.fill() {
+		return false
+	}
+	p.task = p.pending[0]
+	p.pending = p.pending[1:]
+	return true
+}
+
+// fill 读取语句到缓存，在遇到第一条有效语句之前持续读取，以便拒绝无效脚本
+func (p *Parser) fill() bool {
+	for p.err == nil {
+		task, err := p.sp.next()
+		if err != nil {
+			p.err = err
+			break
+		}
+		if task == nil {
+			if !p.valid {
+				p.err = validateTasks(p.pending)
+			}
+			break
+		}
+		p.pending = append(p.pending, *task)
+		if p.valid || isValidTask(*task) {
+			p.valid = true
+			return true
+		}
+	}
+	p.pending = nil
+	return false
+}
+
+// Task 返回当前语句
+func (p *Parser) Task() models.SQLTask {
+	return p.task
+}
+
+// Err 返回解析过程中遇到的错误
+func (p *Parser) Err() error {
+	return p.err
+}
+
+// Close 关闭解析过程中由 @/@@ 打开的脚本，不会关闭传入的 io.Reader
+func (p *Parser) Close() {
+	p.sp.close()
+}
+
+// ParseFile 解析SQL文件
+func ParseFile(path string) ([]models.SQLTask, error) {
+	return ParseFileWithOptions(path, ParseOptions{})
+}
+
+// ParseFileWithOptions 按指定选项解析SQL文件
+func ParseFileWithOptions(path string, opts ParseOptions) ([]models.SQLTask, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	opts.Filename = path
+	parser := ParseReader(file, opts)
+	defer parser.Close()
+
+	var tasks []models.SQLTask
+	for parser.Next() {
+		tasks = append(tasks, parser.Task())
+	}
+	if err := parser.Err(); err != nil {
+		return nil, err
 	}
 
-	return tasks, scanner.Err()
+	return tasks, nil
 }
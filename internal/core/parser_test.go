@@ -1,9 +1,11 @@
 package core
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/iyuangang/oracle-sql-runner/pkg/models"
@@ -30,16 +32,27 @@ func TestParseFile(t *testing.T) {
 			wantErr: false,
 			expected: []models.SQLTask{
 				{
-					SQL:      "SELECT * FROM users",
-					Type:     models.SQLTypeQuery,
-					LineNum:  2,
-					Filename: "", // 将在测试中设置
+					Index:     1,
+					SQL:       "SELECT * FROM users",
+					Type:      models.SQLTypeQuery,
+					Verb:      "SELECT",
+					LineNum:   2,
+					StartLine: 2,
+					EndLine:   2,
+					Text:      "SELECT * FROM users",
+					Filename:  "", // 将在测试中设置
 				},
 				{
-					SQL:      "INSERT INTO users (name) VALUES ('test')",
-					Type:     models.SQLTypeExec,
-					LineNum:  3,
-					Filename: "", // 将在测试中设置
+					Index:     2,
+					SQL:       "INSERT INTO users (name) VALUES ('test')",
+					Type:      models.SQLTypeDML,
+					Verb:      "INSERT",
+					Object:    "USERS",
+					LineNum:   3,
+					StartLine: 3,
+					EndLine:   3,
+					Text:      "INSERT INTO users (name) VALUES ('test')",
+					Filename:  "", // 将在测试中设置
 				},
 			},
 		},
@@ -59,21 +72,39 @@ END;
 			wantErr: false,
 			expected: []models.SQLTask{
 				{
+					Index: 1,
 					SQL: `CREATE OR REPLACE PROCEDURE test_proc AS
 BEGIN
     DBMS_OUTPUT.PUT_LINE('Hello');
 END;`,
-					Type:     models.SQLTypePLSQL,
-					LineNum:  5,
+					Type:      models.SQLTypePLSQL,
+					Verb:      "CREATE PROCEDURE",
+					Object:    "TEST_PROC",
+					LineNum:   5,
+					StartLine: 1,
+					EndLine:   4,
+					Text: `CREATE OR REPLACE PROCEDURE test_proc AS
+BEGIN
+    DBMS_OUTPUT.PUT_LINE('Hello');
+END;`,
 					Filename: "", // 将在测试中设置
 				},
 				{
+					Index: 2,
 					SQL: `CREATE OR REPLACE FUNCTION test_func RETURN NUMBER AS
 BEGIN
     RETURN 1;
 END;`,
-					Type:     models.SQLTypePLSQL,
-					LineNum:  11,
+					Type:      models.SQLTypePLSQL,
+					Verb:      "CREATE FUNCTION",
+					Object:    "TEST_FUNC",
+					LineNum:   11,
+					StartLine: 7,
+					EndLine:   10,
+					Text: `CREATE OR REPLACE FUNCTION test_func RETURN NUMBER AS
+BEGIN
+    RETURN 1;
+END;`,
 					Filename: "", // 将在测试中设置
 				},
 			},
@@ -96,26 +127,48 @@ INSERT INTO test_table VALUES (1);`,
 			wantErr: false,
 			expected: []models.SQLTask{
 				{
-					SQL:      "CREATE TABLE test_table (id NUMBER)",
-					Type:     models.SQLTypeExec,
-					LineNum:  2,
-					Filename: "", // 将在测试中设置
+					Index:     1,
+					SQL:       "CREATE TABLE test_table (id NUMBER)",
+					Type:      models.SQLTypeDDL,
+					Verb:      "CREATE TABLE",
+					Object:    "TEST_TABLE",
+					LineNum:   2,
+					StartLine: 2,
+					EndLine:   2,
+					Text:      "CREATE TABLE test_table (id NUMBER)",
+					Filename:  "", // 将在测试中设置
 				},
 				{
+					Index: 2,
 					SQL: `CREATE OR REPLACE TRIGGER test_trigger
     BEFORE INSERT ON test_table
 BEGIN
     NULL;
 END;`,
-					Type:     models.SQLTypePLSQL,
-					LineNum:  10,
+					Type:      models.SQLTypePLSQL,
+					Verb:      "CREATE TRIGGER",
+					Object:    "TEST_TRIGGER",
+					LineNum:   10,
+					StartLine: 5,
+					EndLine:   9,
+					Text: `CREATE OR REPLACE TRIGGER test_trigger
+    BEFORE INSERT ON test_table
+BEGIN
+    NULL;
+END;`,
 					Filename: "", // 将在测试中设置
 				},
 				{
-					SQL:      "INSERT INTO test_table VALUES (1)",
-					Type:     models.SQLTypeExec,
-					LineNum:  13,
-					Filename: "", // 将在测试中设置
+					Index:     3,
+					SQL:       "INSERT INTO test_table VALUES (1)",
+					Type:      models.SQLTypeDML,
+					Verb:      "INSERT",
+					Object:    "TEST_TABLE",
+					LineNum:   13,
+					StartLine: 13,
+					EndLine:   13,
+					Text:      "INSERT INTO test_table VALUES (1)",
+					Filename:  "", // 将在测试中设置
 				},
 			},
 		},
@@ -136,22 +189,41 @@ END;
 			wantErr: false,
 			expected: []models.SQLTask{
 				{
+					Index: 1,
 					SQL: `CREATE OR REPLACE PACKAGE test_pkg AS
     PROCEDURE test_proc;
 END;`,
-					Type:     models.SQLTypePLSQL,
-					LineNum:  4,
+					Type:      models.SQLTypePLSQL,
+					Verb:      "CREATE PACKAGE",
+					Object:    "TEST_PKG",
+					LineNum:   4,
+					StartLine: 1,
+					EndLine:   3,
+					Text: `CREATE OR REPLACE PACKAGE test_pkg AS
+    PROCEDURE test_proc;
+END;`,
 					Filename: "", // 将在测试中设置
 				},
 				{
+					Index: 2,
 					SQL: `CREATE OR REPLACE PACKAGE BODY test_pkg AS
     PROCEDURE test_proc IS
 BEGIN
     NULL;
 END;
 END;`,
-					Type:     models.SQLTypePLSQL,
-					LineNum:  12,
+					Type:      models.SQLTypePLSQL,
+					Verb:      "CREATE PACKAGE BODY",
+					Object:    "TEST_PKG",
+					LineNum:   12,
+					StartLine: 6,
+					EndLine:   11,
+					Text: `CREATE OR REPLACE PACKAGE BODY test_pkg AS
+    PROCEDURE test_proc IS
+BEGIN
+    NULL;
+END;
+END;`,
 					Filename: "", // 将在测试中设置
 				},
 			},
@@ -180,6 +252,7 @@ END;
 			wantErr: false,
 			expected: []models.SQLTask{
 				{
+					Index: 1,
 					SQL: `DECLARE
     v_count NUMBER;
 BEGIN
@@ -189,8 +262,20 @@ BEGIN
     SELECT COUNT(*) INTO v_count FROM dual;
 END IF;
 END;`,
-					Type:     models.SQLTypePLSQL,
-					LineNum:  10,
+					Type:      models.SQLTypeBlock,
+					Verb:      "DECLARE",
+					LineNum:   10,
+					StartLine: 1,
+					EndLine:   9,
+					Text: `DECLARE
+    v_count NUMBER;
+BEGIN
+    IF 0 = 1 THEN
+        DBMS_OUTPUT.PUT_LINE('This should not be printed');
+    ELSE
+        SELECT COUNT(*) INTO v_count FROM dual;
+    END IF;
+END;`,
 					Filename: "", // 将在测试中设置
 				},
 			},
@@ -201,10 +286,15 @@ END;`,
 			wantErr: false,
 			expected: []models.SQLTask{
 				{
-					SQL:      "SELECT * FROM dual",
-					Type:     models.SQLTypeQuery,
-					LineNum:  1,
-					Filename: "", // 将在测试中设置
+					Index:     1,
+					SQL:       "SELECT * FROM dual",
+					Type:      models.SQLTypeQuery,
+					Verb:      "SELECT",
+					LineNum:   1,
+					StartLine: 1,
+					EndLine:   1,
+					Text:      "SELECT * FROM dual",
+					Filename:  "", // 将在测试中设置
 				},
 			},
 		},
@@ -214,13 +304,275 @@ END;`,
 			wantErr: false,
 			expected: []models.SQLTask{
 				{
-					SQL:      "INSERT INTO test_table VALUES (1)",
-					Type:     models.SQLTypeExec,
-					LineNum:  1,
+					Index:     1,
+					SQL:       "INSERT INTO test_table VALUES (1)",
+					Type:      models.SQLTypeDML,
+					Verb:      "INSERT",
+					Object:    "TEST_TABLE",
+					LineNum:   1,
+					StartLine: 1,
+					EndLine:   1,
+					Text:      "INSERT INTO test_table VALUES (1)",
+					Filename:  "", // 将在测试中设置
+				},
+			},
+		},
+		{
+			name: "字符串和标识符中的分号",
+			content: `INSERT INTO t VALUES ('a;b', q'[c;'d]', "x;y");
+SELECT ';' AS "semi;colon" FROM dual;`,
+			wantErr: false,
+			expected: []models.SQLTask{
+				{
+					Index:     1,
+					SQL:       `INSERT INTO t VALUES ('a;b', q'[c;'d]', "x;y")`,
+					Type:      models.SQLTypeDML,
+					Verb:      "INSERT",
+					Object:    "T",
+					LineNum:   1,
+					StartLine: 1,
+					EndLine:   1,
+					Text:      "INSERT INTO t VALUES ('a;b', q'[c;'d]', \"x;y\")",
+					Filename:  "", // 将在测试中设置
+				},
+				{
+					Index:     2,
+					SQL:       `SELECT ';' AS "semi;colon" FROM dual`,
+					Type:      models.SQLTypeQuery,
+					Verb:      "SELECT",
+					LineNum:   2,
+					StartLine: 2,
+					EndLine:   2,
+					Text:      "SELECT ';' AS \"semi;colon\" FROM dual",
+					Filename:  "", // 将在测试中设置
+				},
+			},
+		},
+		{
+			name: "块注释",
+			content: `/* 头部注释;
+   SELECT 1 FROM dual; */
+SELECT 1 /* 行内; 注释 */ FROM dual;`,
+			wantErr: false,
+			expected: []models.SQLTask{
+				{
+					Index:     1,
+					SQL:       "SELECT 1 /* 行内; 注释 */ FROM dual",
+					Type:      models.SQLTypeQuery,
+					Verb:      "SELECT",
+					LineNum:   3,
+					StartLine: 3,
+					EndLine:   3,
+					Text:      "SELECT 1 /* 行内; 注释 */ FROM dual",
+					Filename:  "", // 将在测试中设置
+				},
+			},
+		},
+		{
+			name:    "同一行多条语句",
+			content: `SELECT 1 FROM dual; SELECT 2 FROM dual;DELETE FROM t`,
+			wantErr: false,
+			expected: []models.SQLTask{
+				{
+					Index:     1,
+					SQL:       "SELECT 1 FROM dual",
+					Type:      models.SQLTypeQuery,
+					Verb:      "SELECT",
+					LineNum:   1,
+					StartLine: 1,
+					EndLine:   1,
+					Text:      "SELECT 1 FROM dual",
+					Filename:  "", // 将在测试中设置
+				},
+				{
+					Index:     2,
+					SQL:       "SELECT 2 FROM dual",
+					Type:      models.SQLTypeQuery,
+					Verb:      "SELECT",
+					LineNum:   1,
+					StartLine: 1,
+					EndLine:   1,
+					Text:      "SELECT 2 FROM dual",
+					Filename:  "", // 将在测试中设置
+				},
+				{
+					Index:     3,
+					SQL:       "DELETE FROM t",
+					Type:      models.SQLTypeDML,
+					Verb:      "DELETE",
+					Object:    "T",
+					LineNum:   1,
+					StartLine: 1,
+					EndLine:   1,
+					Text:      "DELETE FROM t",
+					Filename:  "", // 将在测试中设置
+				},
+			},
+		},
+		{
+			name: "斜杠结束普通语句",
+			content: `UPDATE t SET a = 4 / 2
+/
+SELECT 1 FROM dual`,
+			wantErr: false,
+			expected: []models.SQLTask{
+				{
+					Index:     1,
+					SQL:       "UPDATE t SET a = 4 / 2",
+					Type:      models.SQLTypeDML,
+					Verb:      "UPDATE",
+					Object:    "T",
+					LineNum:   2,
+					StartLine: 1,
+					EndLine:   1,
+					Text:      "UPDATE t SET a = 4 / 2",
+					Filename:  "", // 将在测试中设置
+				},
+				{
+					Index:     2,
+					SQL:       "SELECT 1 FROM dual",
+					Type:      models.SQLTypeQuery,
+					Verb:      "SELECT",
+					LineNum:   3,
+					StartLine: 3,
+					EndLine:   3,
+					Text:      "SELECT 1 FROM dual",
+					Filename:  "", // 将在测试中设置
+				},
+			},
+		},
+		{
+			name: "PL/SQL块中的注释包含斜杠",
+			content: `BEGIN
+    NULL;
+/*
+/
+*/
+END;
+/`,
+			wantErr: false,
+			expected: []models.SQLTask{
+				{
+					Index: 1,
+					SQL: `BEGIN
+    NULL;
+    /*
+    /
+    */
+END;`,
+					Type:      models.SQLTypeBlock,
+					Verb:      "BEGIN",
+					LineNum:   7,
+					StartLine: 1,
+					EndLine:   6,
+					Text: `BEGIN
+    NULL;
+/*
+/
+*/
+END;`,
+					Filename: "", // 将在测试中设置
+				},
+			},
+		},
+		{
+			name: "SQL*Plus命令",
+			content: `SET SERVEROUTPUT ON
+PROMPT it's a "prompt"; not SQL
+REM 这是注释; 不会执行
+WHENEVER SQLERROR EXIT SQL.SQLCODE
+SET TRANSACTION READ ONLY;
+spool out.log
+DEFINE tbs = 'USERS' -
+  extra
+SELECT 1 FROM dual;`,
+			wantErr: false,
+			expected: []models.SQLTask{
+				{
+					SQL:       "SET SERVEROUTPUT ON",
+					Type:      models.SQLTypeCommand,
+					Verb:      "SET",
+					LineNum:   1,
+					StartLine: 1,
+					EndLine:   1,
+					Text:      "SET SERVEROUTPUT ON",
+					Filename:  "", // 将在测试中设置
+				},
+				{
+					SQL:       `PROMPT it's a "prompt"; not SQL`,
+					Type:      models.SQLTypeCommand,
+					Verb:      "PROMPT",
+					LineNum:   2,
+					StartLine: 2,
+					EndLine:   2,
+					Text:      "PROMPT it's a \"prompt\"; not SQL",
+					Filename:  "", // 将在测试中设置
+				},
+				{
+					SQL:       "WHENEVER SQLERROR EXIT SQL.SQLCODE",
+					Type:      models.SQLTypeCommand,
+					Verb:      "WHENEVER",
+					LineNum:   4,
+					StartLine: 4,
+					EndLine:   4,
+					Text:      "WHENEVER SQLERROR EXIT SQL.SQLCODE",
+					Filename:  "", // 将在测试中设置
+				},
+				{
+					Index:     1,
+					SQL:       "SET TRANSACTION READ ONLY",
+					Type:      models.SQLTypeTCL,
+					Verb:      "SET TRANSACTION",
+					LineNum:   5,
+					StartLine: 5,
+					EndLine:   5,
+					Text:      "SET TRANSACTION READ ONLY",
+					Filename:  "", // 将在测试中设置
+				},
+				{
+					SQL:       "spool out.log",
+					Type:      models.SQLTypeCommand,
+					Verb:      "SPOOL",
+					LineNum:   6,
+					StartLine: 6,
+					EndLine:   6,
+					Text:      "spool out.log",
+					Filename:  "", // 将在测试中设置
+				},
+				{
+					SQL:       "DEFINE tbs = 'USERS'   extra",
+					Type:      models.SQLTypeCommand,
+					Verb:      "DEFINE",
+					LineNum:   8,
+					StartLine: 7,
+					EndLine:   8,
+					Text: `DEFINE tbs = 'USERS' -
+  extra`,
 					Filename: "", // 将在测试中设置
 				},
+				{
+					Index:     2,
+					SQL:       "SELECT 1 FROM dual",
+					Type:      models.SQLTypeQuery,
+					Verb:      "SELECT",
+					LineNum:   9,
+					StartLine: 9,
+					EndLine:   9,
+					Text:      "SELECT 1 FROM dual",
+					Filename:  "", // 将在测试中设置
+				},
 			},
 		},
+		{
+			name:    "字符串未闭合",
+			content: "SELECT 'abc FROM dual;",
+			wantErr: true,
+		},
+		{
+			name:    "无效内容",
+			content: "hello world;",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -271,3 +623,146 @@ END;`,
 		}
 	})
 }
+
+func TestParseFixtures(t *testing.T) {
+	tests := []struct {
+		file  string
+		count int
+	}{
+		{file: "basic_query.sql", count: 1},
+		{file: "plsql_block.sql", count: 1},
+		{file: "multi_statements.sql", count: 35},
+		{file: "error_query.sql", count: 1},
+		{file: "one_statement_without_semicolon.sql", count: 1},
+		{file: "call_proc.sql", count: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			tasks, err := ParseFile(filepath.Join("..", "..", "test", "fixtures", tt.file))
+			if err != nil {
+				t.Fatalf("ParseFile() error = %v", err)
+			}
+			if len(tasks) != tt.count {
+				t.Errorf("ParseFile() 返回 %d 条语句, want %d", len(tasks), tt.count)
+			}
+		})
+	}
+}
+
+func TestParseIncludes(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeScript := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("嵌套引用", func(t *testing.T) {
+		tables := writeScript("ddl/tables.sql", "CREATE TABLE &1..t1 (id NUMBER);\n\n@@grants\n")
+		grants := writeScript("ddl/grants.sql", "-- 授权\nGRANT SELECT ON t1 TO app_read;\n")
+		master := writeScript("master.sql", "PROMPT 开始\n@"+tables+" app\n  @@ddl/grants.sql\nSELECT 1 FROM dual;\n")
+
+		tasks, err := ParseFile(master)
+		if err != nil {
+			t.Fatalf("ParseFile() error = %v", err)
+		}
+
+		expected := []models.SQLTask{
+			{SQL: "PROMPT 开始", Type: models.SQLTypeCommand, Verb: "PROMPT", LineNum: 1, StartLine: 1, EndLine: 1, Text: "PROMPT 开始", Filename: master},
+			{Index: 1, SQL: "CREATE TABLE app.t1 (id NUMBER)", Type: models.SQLTypeDDL, Verb: "CREATE TABLE", Object: "APP.T1", LineNum: 1, StartLine: 1, EndLine: 1, Text: "CREATE TABLE &1..t1 (id NUMBER)", Filename: tables},
+			{Index: 2, SQL: "GRANT SELECT ON t1 TO app_read", Type: models.SQLTypeDCL, Verb: "GRANT", Object: "T1", LineNum: 2, StartLine: 2, EndLine: 2, Text: "GRANT SELECT ON t1 TO app_read", Filename: grants},
+			{Index: 3, SQL: "GRANT SELECT ON t1 TO app_read", Type: models.SQLTypeDCL, Verb: "GRANT", Object: "T1", LineNum: 2, StartLine: 2, EndLine: 2, Text: "GRANT SELECT ON t1 TO app_read", Filename: grants},
+			{Index: 4, SQL: "SELECT 1 FROM dual", Type: models.SQLTypeQuery, Verb: "SELECT", LineNum: 4, StartLine: 4, EndLine: 4, Text: "SELECT 1 FROM dual", Filename: master},
+		}
+		if !reflect.DeepEqual(tasks, expected) {
+			t.Errorf("ParseFile() got = %#v, want %#v", tasks, expected)
+		}
+	})
+
+	t.Run("循环引用", func(t *testing.T) {
+		writeScript("cycle/a.sql", "SELECT 1 FROM dual;\n@@b.sql\n")
+		writeScript("cycle/b.sql", "@@a\n")
+		_, err := ParseFile(filepath.Join(tmpDir, "cycle", "a.sql"))
+		if err == nil || !strings.Contains(err.Error(), "检测到循环引用") {
+			t.Fatalf("ParseFile() error = %v, want 循环引用", err)
+		}
+		if !strings.Contains(err.Error(), "b.sql: 第 1 行") {
+			t.Errorf("错误信息应指向引用所在的文件和行: %v", err)
+		}
+	})
+
+	t.Run("脚本不存在", func(t *testing.T) {
+		path := writeScript("missing.sql", "SELECT 1 FROM dual;\n@@nothing.sql\n")
+		_, err := ParseFile(path)
+		if err == nil || !strings.Contains(err.Error(), "第 2 行: 打开脚本失败") {
+			t.Fatalf("ParseFile() error = %v, want 打开脚本失败", err)
+		}
+	})
+}
+
+func TestParseReader(t *testing.T) {
+	t.Run("边读取边返回", func(t *testing.T) {
+		r, w := io.Pipe()
+		parser := ParseReader(r, ParseOptions{Filename: "stream.sql"})
+		defer parser.Close()
+
+		go func() {
+			io.WriteString(w, "INSERT INTO t VALUES (1);\n")
+		}()
+		if !parser.Next() {
+			t.Fatalf("Next() = false, err = %v", parser.Err())
+		}
+		if got := parser.Task(); got.SQL != "INSERT INTO t VALUES (1)" || got.LineNum != 1 || got.Filename != "stream.sql" {
+			t.Errorf("Task() = %#v", got)
+		}
+
+		go func() {
+			io.WriteString(w, "INSERT INTO t VALUES (2);\n")
+			w.Close()
+		}()
+		if !parser.Next() || parser.Task().SQL != "INSERT INTO t VALUES (2)" {
+			t.Fatalf("Next() 未返回第二条语句, err = %v", parser.Err())
+		}
+		if parser.Next() {
+			t.Errorf("Next() 应在脚本结束时返回 false")
+		}
+		if err := parser.Err(); err != nil {
+			t.Errorf("Err() = %v", err)
+		}
+	})
+
+	t.Run("超长行", func(t *testing.T) {
+		values := strings.Repeat("'x', ", 50000)
+		content := "INSERT INTO t VALUES (" + values + "'y');\nSELECT 1 FROM dual;\n"
+		parser := ParseReader(strings.NewReader(content), ParseOptions{})
+		defer parser.Close()
+
+		var tasks []models.SQLTask
+		for parser.Next() {
+			tasks = append(tasks, parser.Task())
+		}
+		if err := parser.Err(); err != nil {
+			t.Fatalf("Err() = %v", err)
+		}
+		if len(tasks) != 2 || len(tasks[0].SQL) <= 64*1024 || tasks[1].LineNum != 2 {
+			t.Errorf("超长行解析结果不正确: %d 条语句", len(tasks))
+		}
+	})
+
+	t.Run("无效内容", func(t *testing.T) {
+		parser := ParseReader(strings.NewReader("hello world;\nfoo bar;\n"), ParseOptions{})
+		defer parser.Close()
+		if parser.Next() {
+			t.Errorf("无效脚本不应返回语句: %#v", parser.Task())
+		}
+		if err := parser.Err(); err == nil || !strings.Contains(err.Error(), "未找到有效的SQL语句") {
+			t.Errorf("Err() = %v", err)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/godror/godror"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// plsqlErrorPattern 匹配 ORA-06550 中 PL/SQL 编译错误的行列号
+var plsqlErrorPattern = regexp.MustCompile(`ORA-06550: line (\d+), column (\d+)`)
+
+// withPosition 在错误中附加出错位置，无法确定位置时原样返回
+func withPosition(task models.SQLTask, err error) error {
+	if err == nil || task.Text == "" {
+		return err
+	}
+	line, col, ok := sqlErrorPosition(task.SQL, err)
+	if !ok {
+		return err
+	}
+	pos, ok := sourcePosition(task, line, col)
+	if !ok {
+		return err
+	}
+	return &models.PositionError{Err: err, Position: pos}
+}
+
+// sqlErrorPosition 返回错误在执行的SQL文本中的行列号（从 1 开始）
+func sqlErrorPosition(sql string, err error) (int, int, bool) {
+	if match := plsqlErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		col, _ := strconv.Atoi(match[2])
+		return line, col, line > 0 && col > 0
+	}
+	if oraErr, ok := godror.AsOraErr(err); ok && oraErr.Offset() > 0 {
+		return offsetPosition(sql, oraErr.Offset())
+	}
+	return 0, 0, false
+}
+
+// offsetPosition 将字节偏移转换为行列号
+func offsetPosition(sql string, offset int) (int, int, bool) {
+	if offset < 0 || offset > len(sql) {
+		return 0, 0, false
+	}
+	before := sql[:offset]
+	line := strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCountInString(before[lineStart:]) + 1, true
+}
+
+// sourcePosition 将执行文本中的行列号映射回源文件
+//
+// 普通语句执行的文本与原文逐行对应；PL/SQL 经 normalizeSQL 去除了空行并调整了缩进，
+// 按非空行的顺序对应，列号根据两边的缩进差值换算。
+func sourcePosition(task models.SQLTask, line, col int) (models.SourcePosition, bool) {
+	sqlLines := strings.Split(task.SQL, "\n")
+	srcLines := strings.Split(task.Text, "\n")
+	if line < 1 || line > len(sqlLines) {
+		return models.SourcePosition{}, false
+	}
+
+	srcIndex := line - 1
+	if task.Type.IsPLSQL() {
+		srcIndex = nthNonBlankLine(srcLines, line)
+		sqlLine := sqlLines[line-1]
+		sqlIndent := utf8.RuneCountInString(sqlLine) - utf8.RuneCountInString(strings.TrimLeft(sqlLine, " \t"))
+		if srcIndex >= 0 {
+			srcLine := srcLines[srcIndex]
+			srcIndent := utf8.RuneCountInString(srcLine) - utf8.RuneCountInString(strings.TrimLeft(srcLine, " \t"))
+			col += srcIndent - sqlIndent
+		}
+	}
+	if srcIndex < 0 || srcIndex >= len(srcLines) || col < 1 {
+		return models.SourcePosition{}, false
+	}
+
+	return models.SourcePosition{
+		Line:   task.StartLine + srcIndex,
+		Column: col,
+		Source: strings.TrimRight(srcLines[srcIndex], "\r"),
+	}, true
+}
+
+// nthNonBlankLine 返回第 n 个非空行的下标，不存在时返回 -1
+func nthNonBlankLine(lines []string, n int) int {
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n--
+		if n == 0 {
+			return i
+		}
+	}
+	return -1
+}
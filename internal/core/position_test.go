@@ -0,0 +1,60 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseOne 解析只包含一条语句的脚本
+func parseOne(t *testing.T, content string) models.SQLTask {
+	t.Helper()
+	parser := ParseReader(strings.NewReader(content), ParseOptions{Filename: "test.sql"})
+	defer parser.Close()
+	require.True(t, parser.Next(), "解析失败: %v", parser.Err())
+	return parser.Task()
+}
+
+func TestOffsetPosition(t *testing.T) {
+	line, col, ok := offsetPosition("SELECT *\n  FROM 用户表 x", 19)
+	require.True(t, ok)
+	assert.Equal(t, 2, line)
+	assert.Equal(t, 9, col)
+
+	_, _, ok = offsetPosition("SELECT 1", 100)
+	assert.False(t, ok)
+}
+
+func TestSourcePosition(t *testing.T) {
+	t.Run("普通语句", func(t *testing.T) {
+		task := parseOne(t, "\n\n-- 查询\nSELECT *\n  FROM missing_table\n WHERE 1 = 1;\n")
+		assert.Equal(t, 4, task.StartLine)
+		assert.Equal(t, 6, task.EndLine)
+
+		pos, ok := sourcePosition(task, 2, 8)
+		require.True(t, ok)
+		assert.Equal(t, models.SourcePosition{Line: 5, Column: 8, Source: "  FROM missing_table"}, pos)
+	})
+
+	t.Run("PL/SQL块", func(t *testing.T) {
+		task := parseOne(t, "BEGIN\n\n        UPDATE t SET x = 1;\n\n        y := 2;\nEND;\n/\n")
+		// normalizeSQL 去除了空行并将缩进调整为 4 个空格
+		require.Equal(t, "BEGIN\n    UPDATE t SET x = 1;\n    y := 2;\nEND;", task.SQL)
+
+		err := withPosition(task, errors.New("ORA-06550: line 3, column 5:\nPLS-00201: identifier 'Y' must be declared"))
+		var posErr *models.PositionError
+		require.True(t, errors.As(err, &posErr))
+		assert.Equal(t, models.SourcePosition{Line: 5, Column: 9, Source: "        y := 2;"}, posErr.Position)
+		assert.Contains(t, err.Error(), "PLS-00201")
+	})
+
+	t.Run("无位置信息", func(t *testing.T) {
+		task := parseOne(t, "SELECT 1 FROM dual;")
+		err := errors.New("ORA-01017: invalid username/password")
+		assert.Same(t, err, withPosition(task, err))
+	})
+}
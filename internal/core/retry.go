@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// retryableOraCodes 默认可重试的 Oracle 错误码，均表示数据库不可用或连接中断
+var retryableOraCodes = map[int]bool{
+	1033:  true, // ORACLE initialization or shutdown in progress
+	1034:  true, // ORACLE not available
+	1089:  true, // immediate shutdown in progress
+	1090:  true, // shutdown in progress
+	1092:  true, // ORACLE instance terminated
+	3113:  true, // end-of-file on communication channel
+	3114:  true, // not connected to ORACLE
+	3135:  true, // connection lost contact
+	12153: true, // TNS:not connected
+	12154: true, // TNS:could not resolve service name
+	12170: true, // TNS:Connect timeout occurred
+	12171: true, // TNS:could not resolve connect identifier
+	12257: true, // TNS:protocol adapter not loadable
+	12514: true, // TNS:listener does not currently know of service requested
+	12528: true, // TNS:listener: all appropriate instances are blocking new connections
+	12537: true, // TNS:connection closed
+	12541: true, // TNS:no listener
+	12571: true, // TNS:packet writer failure
+}
+
+// networkErrors 可重试的网络错误
+var networkErrors = []string{
+	"connection refused",
+	"connection reset",
+	"connection timed out",
+	"broken pipe",
+	"no route to host",
+	"network is unreachable",
+}
+
+// lostConnectionCodes 语句执行期间连接中断的错误码，此时语句可能已在数据库中生效
+var lostConnectionCodes = map[int]bool{
+	1089:  true, // immediate shutdown in progress
+	1092:  true, // ORACLE instance terminated
+	3113:  true, // end-of-file on communication channel
+	3114:  true, // not connected to ORACLE
+	3135:  true, // connection lost contact
+	12537: true, // TNS:connection closed
+	12571: true, // TNS:packet writer failure
+}
+
+// lostConnectionErrors 表示连接中断的网络错误
+var lostConnectionErrors = []string{
+	"connection reset",
+	"connection timed out",
+	"broken pipe",
+}
+
+// errOutcomeUnknown 连接中断时语句可能已经提交，重新执行可能重复修改数据
+var errOutcomeUnknown = errors.New("连接中断，无法确定语句是否已经提交，未自动重试")
+
+// outcomeUnknown 包装执行结果未知的错误
+func outcomeUnknown(err error) error {
+	return fmt.Errorf("%w: %w", errOutcomeUnknown, err)
+}
+
+// connectionLost 判断错误是否表示执行期间连接中断
+func connectionLost(err error) bool {
+	if code, ok := oraErrorCode(err); ok {
+		return lostConnectionCodes[code]
+	}
+	errStr := strings.ToLower(err.Error())
+	for _, netErr := range lostConnectionErrors {
+		if strings.Contains(errStr, netErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaySafe 判断自动提交的语句在连接中断后能否重新执行
+//
+// 查询和会话设置不修改数据；其他语句可能在连接中断前已经提交，只有标记了
+// @runner:idempotent 的语句才会重试。
+func replaySafe(task models.SQLTask) bool {
+	return task.Type == models.SQLTypeQuery || isSessionStatement(task) || task.Directives.Idempotent
+}
+
+// retryPolicy 单条语句的重试策略
+type retryPolicy struct {
+	maxRetries int           // 最大重试次数，不含首次执行
+	baseDelay  time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	maxDelay   time.Duration // 单次等待时间上限
+	maxElapsed time.Duration // 从首次执行起允许重试的总时长，0 表示不限制
+	codes      map[int]bool  // 配置中额外的可重试错误码
+}
+
+// retryPolicy 根据配置和语句指令生成重试策略
+func (e *Executor) retryPolicy(task models.SQLTask) retryPolicy {
+	p := retryPolicy{
+		maxRetries: e.config.Retries(),
+		baseDelay:  time.Duration(e.config.Retry.BaseDelay) * time.Millisecond,
+		maxDelay:   time.Duration(e.config.Retry.MaxDelay) * time.Millisecond,
+		maxElapsed: time.Duration(e.config.Retry.MaxElapsed) * time.Second,
+		codes:      make(map[int]bool),
+	}
+	if task.Directives.Retries != nil {
+		p.maxRetries = *task.Directives.Retries
+	}
+	for _, code := range e.config.Retry.Codes {
+		p.codes[code] = true
+	}
+	return p
+}
+
+// retryable 判断错误是否可以重试
+func (p retryPolicy) retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if code, ok := oraErrorCode(err); ok {
+		return retryableOraCodes[code] || p.codes[code]
+	}
+
+	errStr := strings.ToLower(err.Error())
+	for _, netErr := range networkErrors {
+		if strings.Contains(errStr, netErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff 返回第 retry 次重试前的等待时间
+//
+// 等待时间按指数增长并以 maxDelay 为上限，实际取值在其一半到全部之间随机，
+// 避免多个工作协程在数据库恢复时同时重试。
+func (p retryPolicy) backoff(retry int) time.Duration {
+	delay := p.baseDelay
+	for i := 1; i < retry && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// next 判断失败后是否重试，返回第 retry 次重试前的等待时间
+func (p retryPolicy) next(retry int, elapsed time.Duration, err error) (time.Duration, bool) {
+	if retry > p.maxRetries || !p.retryable(err) {
+		return 0, false
+	}
+	delay := p.backoff(retry)
+	if p.maxElapsed > 0 && elapsed+delay > p.maxElapsed {
+		return 0, false
+	}
+	return delay, true
+}
+
+// sleep 等待指定时间，ctx 结束时提前返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
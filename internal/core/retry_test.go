@@ -0,0 +1,167 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iyuangang/oracle-sql-runner/internal/config"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRetryExecutor(codes ...int) *Executor {
+	return &Executor{config: &config.Config{
+		Retry: config.RetryConfig{BaseDelay: 100, MaxDelay: 1000, MaxElapsed: 10, Codes: codes},
+	}}
+}
+
+func TestRetryable(t *testing.T) {
+	policy := newRetryExecutor(60).retryPolicy(models.SQLTask{})
+
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errors.New("ORA-03113: end-of-file on communication channel"), want: true},
+		{err: fmt.Errorf("查询执行失败: %w", errors.New("ORA-12541: TNS:no listener")), want: true},
+		{err: errors.New("ORA-00942: table or view does not exist"), want: false},
+		{err: errors.New("ORA-00060: deadlock detected while waiting for resource"), want: true},
+		{err: errors.New("read tcp 10.0.0.1:1521: connection reset by peer"), want: true},
+		{err: errors.New("syntax error"), want: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, policy.retryable(tt.err), "%v", tt.err)
+	}
+
+	assert.False(t, newRetryExecutor().retryPolicy(models.SQLTask{}).retryable(tests[4].err), "未配置的错误码不重试")
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := newRetryExecutor().retryPolicy(models.SQLTask{})
+
+	for i := 0; i < 20; i++ {
+		for retry, want := range map[int]time.Duration{1: 100, 2: 200, 3: 400, 4: 800, 5: 1000, 10: 1000} {
+			want *= time.Millisecond
+			delay := policy.backoff(retry)
+			assert.GreaterOrEqual(t, delay, want/2, "第 %d 次重试", retry)
+			assert.LessOrEqual(t, delay, want, "第 %d 次重试", retry)
+		}
+	}
+
+	assert.Zero(t, retryPolicy{}.backoff(3))
+}
+
+func TestRetryNext(t *testing.T) {
+	connErr := errors.New("ORA-03114: not connected to ORACLE")
+	e := newRetryExecutor()
+	policy := e.retryPolicy(models.SQLTask{})
+
+	for retry := 1; retry <= 3; retry++ {
+		_, ok := policy.next(retry, 0, connErr)
+		assert.True(t, ok, "第 %d 次重试", retry)
+	}
+	_, ok := policy.next(4, 0, connErr)
+	assert.False(t, ok, "超过 max_retries")
+
+	_, ok = policy.next(1, 0, errors.New("ORA-00001: unique constraint violated"))
+	assert.False(t, ok, "不可重试的错误")
+
+	_, ok = policy.next(1, 10*time.Second, connErr)
+	assert.False(t, ok, "超过 max_elapsed")
+
+	retries := 0
+	policy = e.retryPolicy(models.SQLTask{Directives: models.Directives{Retries: &retries}})
+	_, ok = policy.next(1, 0, connErr)
+	assert.False(t, ok, "@runner:retries 0 覆盖配置")
+}
+
+func TestConnectionLost(t *testing.T) {
+	assert.True(t, connectionLost(errors.New("ORA-03113: end-of-file on communication channel")))
+	assert.True(t, connectionLost(errors.New("write tcp 10.0.0.1:1521: broken pipe")))
+	assert.False(t, connectionLost(errors.New("ORA-12541: TNS:no listener")), "建立连接失败时语句没有执行")
+	assert.False(t, connectionLost(errors.New("ORA-00060: deadlock detected while waiting for resource")))
+}
+
+func TestReplaySafe(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{sql: "SELECT * FROM t;", want: true},
+		{sql: "ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD';", want: true},
+		{sql: "INSERT INTO t VALUES (1);", want: false},
+		{sql: "CREATE TABLE t (id NUMBER);", want: false},
+		{sql: "-- @runner:idempotent\nCREATE TABLE t (id NUMBER);", want: true},
+		{sql: "-- @runner:idempotent\nMERGE INTO t USING s ON (t.id = s.id) WHEN NOT MATCHED THEN INSERT VALUES (s.id);", want: true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, replaySafe(parseOne(t, tt.sql)), tt.sql)
+	}
+}
+
+// stubQuerier 依次返回预设错误的执行目标，记录收到的查询
+type stubQuerier struct {
+	errs    []error
+	calls   int
+	queries []string
+	args    [][]interface{}
+}
+
+func (q *stubQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	q.calls++
+	if q.calls <= len(q.errs) {
+		return nil, q.errs[q.calls-1]
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (q *stubQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	q.queries = append(q.queries, query)
+	q.args = append(q.args, args)
+	return nil, errors.New("不支持查询")
+}
+
+func (q *stubQuerier) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("不支持预处理")
+}
+
+func TestExecuteTaskRetry(t *testing.T) {
+	lost := errors.New("ORA-03113: end-of-file on communication channel")
+	deadlock := errors.New("ORA-00060: deadlock detected while waiting for resource")
+
+	tests := []struct {
+		name        string
+		sql         string
+		errs        []error
+		wantCalls   int
+		wantRetries int
+		wantUnknown bool
+		wantErr     bool
+	}{
+		{name: "自动提交的DML连接中断", sql: "UPDATE t SET a = 1;", errs: []error{lost}, wantCalls: 1, wantUnknown: true, wantErr: true},
+		{name: "幂等语句连接中断", sql: "-- @runner:idempotent\nUPDATE t SET a = 1;", errs: []error{lost}, wantCalls: 2, wantRetries: 1},
+		{name: "死锁后重试DML", sql: "UPDATE t SET a = 1;", errs: []error{deadlock, deadlock}, wantCalls: 3, wantRetries: 2},
+		{name: "不可重试的错误", sql: "UPDATE t SET a = 1;", errs: []error{errors.New("ORA-00001: unique constraint violated")}, wantCalls: 1, wantErr: true},
+	}
+
+	e := newRetryExecutor(60)
+	e.config.Retry.BaseDelay = 1
+	e.config.Retry.MaxDelay = 1
+	e.logger = newCommandExecutor(t).logger
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &stubQuerier{errs: tt.errs}
+			stats, err := e.executeTaskWithOutput(context.Background(), q, parseOne(t, tt.sql), &outputCapture{})
+			assert.Equal(t, tt.wantCalls, q.calls)
+			assert.Equal(t, tt.wantRetries, stats.retries)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantUnknown, errors.Is(err, errOutcomeUnknown))
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// access 语句读写的数据库对象，对象按不带模式名的名称记录
+//
+// 读集合保守地包含语句中出现的所有标识符，多出的列名等只会减少并行度，不会引入竞争。
+// 修改数据的DML还可能通过外键和触发器访问文本中没有出现的表，相互之间总是按脚本顺序执行。
+type access struct {
+	reads    map[string]bool
+	writes   map[string]bool
+	modifies bool // 修改表数据的DML
+	barrier  bool // 无法确定影响范围，需要等待之前的语句完成并阻塞之后的语句
+}
+
+// taskAccess 分析语句读写的对象
+func taskAccess(task models.SQLTask) access {
+	a := access{reads: make(map[string]bool), writes: make(map[string]bool)}
+	tokens := significantTokens(task.SQL)
+	c := &tokenCursor{tokens: tokens}
+	for c.pos < len(tokens) {
+		if part, ok := c.namePart(); ok {
+			a.reads[part] = true
+		} else {
+			c.advance()
+		}
+	}
+
+	switch task.Type {
+	case models.SQLTypeQuery:
+		return a
+	case models.SQLTypeDML, models.SQLTypeDDL, models.SQLTypeDCL, models.SQLTypePLSQL:
+		if task.Verb == "CALL" || task.Verb == "EXPLAIN PLAN" || task.Object == "" {
+			break
+		}
+		a.writes[objectKey(task.Object)] = true
+		a.modifies = task.Type == models.SQLTypeDML
+		if task.Verb == "INSERT" {
+			// INSERT ALL/FIRST 写入每个 INTO 之后的表
+			c = &tokenCursor{tokens: tokens}
+			for c.skipTo("INTO") {
+				if name := c.name(); name != "" {
+					a.writes[objectKey(name)] = true
+				}
+			}
+		}
+		return a
+	}
+
+	// 事务控制、匿名块、会话设置等语句的影响无法从文本确定
+	a.barrier = true
+	return a
+}
+
+// objectKey 返回对象名中不带模式名的部分
+func objectKey(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// conflicts 判断两条语句是否访问了同一对象且至少一方写入，或者都修改表数据
+func (a access) conflicts(b access) bool {
+	if a.barrier || b.barrier || (a.modifies && b.modifies) {
+		return true
+	}
+	for name := range a.writes {
+		if b.reads[name] || b.writes[name] {
+			return true
+		}
+	}
+	for name := range b.writes {
+		if a.reads[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencies 返回每个任务需要等待完成的前序任务下标
+//
+// 屏障语句依赖上一个屏障之后的所有语句，其后的语句都依赖该屏障；
+// 其他语句依赖上一个屏障以及之后与其冲突的语句。
+func dependencies(tasks []models.SQLTask) [][]int {
+	deps := make([][]int, len(tasks))
+	accesses := make([]access, len(tasks))
+	barrier := -1
+	for i, task := range tasks {
+		accesses[i] = taskAccess(task)
+		if barrier >= 0 {
+			deps[i] = append(deps[i], barrier)
+		}
+		for j := barrier + 1; j < i; j++ {
+			if accesses[i].conflicts(accesses[j]) {
+				deps[i] = append(deps[i], j)
+			}
+		}
+		if accesses[i].barrier {
+			barrier = i
+		}
+	}
+	return deps
+}
+
+// scheduler 按依赖关系分发任务，依赖的任务全部完成后才能开始执行
+//
+// 就绪的任务按脚本顺序分发。window 大于 0 时，只分发与最早未完成任务的距离小于
+// window 的任务，限制等待按顺序输出的任务数；最早未完成的任务总是可以分发，不会死锁。
+type scheduler struct {
+	mu           sync.Mutex
+	cond         *sync.Cond
+	waiting      []int   // 每个任务尚未完成的依赖数
+	dependents   [][]int // 每个任务完成后需要通知的任务
+	finished     []bool
+	ready        []int // 已就绪尚未分发的任务，按下标升序排列
+	undispatched int
+	head         int // 最早尚未完成的任务
+	window       int
+}
+
+// newScheduler 创建调度器，deps 为空时所有任务立即就绪，window 为 0 时不限制
+func newScheduler(count int, deps [][]int, window int) *scheduler {
+	s := &scheduler{
+		waiting:      make([]int, count),
+		dependents:   make([][]int, count),
+		finished:     make([]bool, count),
+		undispatched: count,
+		window:       window,
+	}
+	s.cond = sync.NewCond(&s.mu)
+	for i, list := range deps {
+		s.waiting[i] = len(list)
+		for _, j := range list {
+			s.dependents[j] = append(s.dependents[j], i)
+		}
+	}
+	for i := 0; i < count; i++ {
+		if s.waiting[i] == 0 {
+			s.ready = append(s.ready, i)
+		}
+	}
+	return s
+}
+
+// take 取出下一个可以执行的任务，所有任务都已分发时返回 false
+func (s *scheduler) take() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.undispatched > 0 {
+		if len(s.ready) > 0 && (s.window <= 0 || s.ready[0] < s.head+s.window) {
+			i := s.ready[0]
+			s.ready = s.ready[1:]
+			s.undispatched--
+			return i, true
+		}
+		s.cond.Wait()
+	}
+	return 0, false
+}
+
+// done 标记任务完成，释放依赖它的任务
+func (s *scheduler) done(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished[i] = true
+	for s.head < len(s.finished) && s.finished[s.head] {
+		s.head++
+	}
+	for _, j := range s.dependents[i] {
+		s.waiting[j]--
+		if s.waiting[j] == 0 {
+			k := sort.SearchInts(s.ready, j)
+			s.ready = append(s.ready, 0)
+			copy(s.ready[k+1:], s.ready[k:])
+			s.ready[k] = j
+		}
+	}
+	s.cond.Broadcast()
+}
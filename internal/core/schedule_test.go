@@ -0,0 +1,168 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseTasks 解析脚本内容并返回全部语句
+func parseTasks(t *testing.T, content string) []models.SQLTask {
+	t.Helper()
+	parser := ParseReader(strings.NewReader(content), ParseOptions{})
+	defer parser.Close()
+
+	var tasks []models.SQLTask
+	for parser.Next() {
+		tasks = append(tasks, parser.Task())
+	}
+	require.NoError(t, parser.Err())
+	return tasks
+}
+
+func TestTaskAccess(t *testing.T) {
+	tests := []struct {
+		sql      string
+		writes   []string
+		reads    []string
+		modifies bool
+		barrier  bool
+	}{
+		{sql: "SELECT name FROM app.users u JOIN orders o ON o.uid = u.id", reads: []string{"APP", "USERS", "ORDERS"}},
+		{sql: "INSERT INTO app.users (id) SELECT id FROM staging", writes: []string{"USERS"}, reads: []string{"STAGING"}, modifies: true},
+		{sql: "INSERT ALL INTO t1 VALUES (1) INTO t2 VALUES (2) SELECT * FROM dual", writes: []string{"T1", "T2"}, modifies: true},
+		{sql: `UPDATE "Users" SET a = 1`, writes: []string{"Users"}, modifies: true},
+		{sql: "DELETE FROM emp WHERE deptno = 10", writes: []string{"EMP"}, modifies: true},
+		{sql: "CREATE TABLE t (id NUMBER)", writes: []string{"T"}},
+		{sql: "CREATE INDEX idx ON t (id)", writes: []string{"IDX"}, reads: []string{"T"}},
+		{sql: "CREATE OR REPLACE VIEW v AS SELECT * FROM t", writes: []string{"V"}, reads: []string{"T"}},
+		{sql: "GRANT SELECT ON t TO r", writes: []string{"T"}},
+		{sql: "COMMIT", barrier: true},
+		{sql: "ROLLBACK", barrier: true},
+		{sql: "BEGIN NULL; END;", barrier: true},
+		{sql: "ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD'", barrier: true},
+		{sql: "GRANT CREATE SESSION TO u", barrier: true},
+		{sql: "CALL p()", barrier: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			info := classify(tt.sql)
+			a := taskAccess(models.SQLTask{SQL: tt.sql, Type: info.sqlType, Verb: info.verb, Object: info.object})
+			assert.Equal(t, tt.barrier, a.barrier)
+			assert.Equal(t, tt.modifies, a.modifies)
+			if tt.barrier {
+				return
+			}
+			var writes []string
+			for name := range a.writes {
+				writes = append(writes, name)
+			}
+			assert.ElementsMatch(t, tt.writes, writes)
+			for _, name := range tt.reads {
+				assert.True(t, a.reads[name], "应读取 %s", name)
+			}
+		})
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	tasks := parseTasks(t, `CREATE TABLE test_table (id NUMBER, name VARCHAR2(50));
+CREATE TABLE other_table (id NUMBER);
+INSERT INTO test_table VALUES (1, 'a');
+INSERT INTO other_table VALUES (1);
+SELECT * FROM test_table;
+SELECT * FROM other_table;
+COMMIT;
+SELECT COUNT(*) FROM dual;
+DROP TABLE test_table;
+`)
+	require.Len(t, tasks, 9)
+
+	deps := dependencies(tasks)
+	assert.Empty(t, deps[0])
+	assert.Empty(t, deps[1], "不同表的建表语句相互独立")
+	assert.Equal(t, []int{0}, deps[2])
+	assert.Equal(t, []int{1, 2}, deps[3], "修改不同表的DML同样按顺序执行")
+	assert.Equal(t, []int{0, 2}, deps[4])
+	assert.Equal(t, []int{1, 3}, deps[5])
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, deps[6], "COMMIT 等待之前的所有语句")
+	assert.Equal(t, []int{6}, deps[7], "COMMIT 之后的语句等待 COMMIT")
+	assert.Equal(t, []int{6}, deps[8])
+}
+
+func TestDependenciesModifyingDML(t *testing.T) {
+	// emp 通过外键引用 dept，文本中看不出两条 INSERT 之间的依赖
+	tasks := parseTasks(t, `INSERT INTO dept VALUES (10, 'SALES');
+SELECT COUNT(*) FROM audit_log;
+INSERT INTO emp VALUES (1, 'SMITH', 10);
+CREATE INDEX idx_bonus ON bonus (empno);
+MERGE INTO bonus b USING emp e ON (b.empno = e.empno) WHEN NOT MATCHED THEN INSERT VALUES (e.empno, 0);
+`)
+	require.Len(t, tasks, 5)
+
+	deps := dependencies(tasks)
+	assert.Empty(t, deps[1], "查询不等待修改其他表的DML")
+	assert.Equal(t, []int{0}, deps[2], "修改不同表的DML按脚本顺序执行")
+	assert.Empty(t, deps[3], "DDL 不等待修改其他对象的DML")
+	assert.Equal(t, []int{0, 2, 3}, deps[4])
+}
+
+func TestScheduler(t *testing.T) {
+	deps := [][]int{nil, nil, {0}, {1}, {0, 2}, {2, 3, 4}}
+
+	for _, window := range []int{0, 1, 2} {
+		t.Run(fmt.Sprintf("window=%d", window), func(t *testing.T) {
+			s := newScheduler(len(deps), deps, window)
+
+			var mu sync.Mutex
+			finished := make(map[int]bool)
+			var order []int
+
+			var wg sync.WaitGroup
+			for w := 0; w < 3; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						i, ok := s.take()
+						if !ok {
+							return
+						}
+						mu.Lock()
+						for _, dep := range deps[i] {
+							assert.True(t, finished[dep], "任务 %d 在依赖 %d 完成前开始执行", i, dep)
+						}
+						head := 0
+						for finished[head] {
+							head++
+						}
+						if window > 0 {
+							assert.Less(t, i, head+window, "任务 %d 超出窗口", i)
+						}
+						finished[i] = true
+						order = append(order, i)
+						mu.Unlock()
+						s.done(i)
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Len(t, order, len(deps))
+			assert.Equal(t, 5, order[len(order)-1])
+			if window == 1 {
+				assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, order, "窗口为 1 时按脚本顺序执行")
+			}
+		})
+	}
+
+	empty := newScheduler(0, nil, 0)
+	_, ok := empty.take()
+	assert.False(t, ok)
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/godror/godror"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// producesServerOutput 判断任务执行后是否需要读取 DBMS_OUTPUT 缓冲
+func producesServerOutput(task models.SQLTask) bool {
+	return task.Type.IsPLSQL() || task.Verb == "CALL"
+}
+
+// readServerOutput 读取并清空会话中 DBMS_OUTPUT 缓冲的内容
+func readServerOutput(ctx context.Context, q querier) ([]string, error) {
+	var buf strings.Builder
+	if err := godror.ReadDbmsOutput(ctx, &buf, q); err != nil {
+		return nil, err
+	}
+	if buf.Len() == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"), nil
+}
+
+// drainServerOutput 读取 DBMS_OUTPUT 内容并写入输出，SET SERVEROUTPUT OFF 时丢弃
+func (e *Executor) drainServerOutput(q querier, state *scriptState, output io.Writer) []string {
+	lines, err := readServerOutput(context.Background(), q)
+	if err != nil {
+		e.logger.Warn("读取DBMS_OUTPUT失败", "error", err)
+		return nil
+	}
+	if !state.serverOutput {
+		return nil
+	}
+	for _, line := range lines {
+		fmt.Fprintln(output, line)
+	}
+	return lines
+}
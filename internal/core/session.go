@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/godror/godror"
+	"github.com/iyuangang/oracle-sql-runner/internal/db"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// isSessionStatement 判断语句是否修改会话状态，此类语句单独在脚本会话上执行，
+// 并在并行执行时重放到每个工作连接上
+func isSessionStatement(task models.SQLTask) bool {
+	return task.Verb == "ALTER SESSION" || task.Verb == "SET ROLE"
+}
+
+// session 固定在单个连接上的数据库会话，连接失效后重新获取连接并重放会话设置
+type session struct {
+	pool  *db.Pool
+	conn  *db.Conn
+	setup []string // 已执行的会话设置语句
+}
+
+// openSession 从连接池获取连接并依次执行会话设置语句
+func openSession(ctx context.Context, pool *db.Pool, setup []string) (*session, error) {
+	s := &session{pool: pool, setup: append([]string(nil), setup...)}
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect 获取新连接并重放会话设置
+func (s *session) connect(ctx context.Context) error {
+	conn, err := s.pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	// 启用 DBMS_OUTPUT 缓冲，执行 PL/SQL 后读取其输出
+	if err := godror.EnableDbmsOutput(ctx, conn); err != nil {
+		conn.Discard()
+		return fmt.Errorf("启用DBMS_OUTPUT失败: %w", err)
+	}
+	for _, stmt := range s.setup {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			conn.Discard()
+			return fmt.Errorf("重放会话设置失败: %s: %w", stmt, err)
+		}
+	}
+	s.conn = conn
+	return nil
+}
+
+// reconnect 丢弃失效的连接，重新建立会话
+func (s *session) reconnect(ctx context.Context) error {
+	if s.conn != nil {
+		s.conn.Discard()
+		s.conn = nil
+	}
+	return s.connect(ctx)
+}
+
+// ExecContext 在会话上执行SQL语句
+func (s *session) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if s.conn == nil {
+		return nil, fmt.Errorf("数据库会话已断开")
+	}
+	return s.conn.ExecContext(ctx, query, args...)
+}
+
+// QueryContext 在会话上执行查询
+func (s *session) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if s.conn == nil {
+		return nil, fmt.Errorf("数据库会话已断开")
+	}
+	return s.conn.QueryContext(ctx, query, args...)
+}
+
+// PrepareContext 在会话上准备语句
+func (s *session) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if s.conn == nil {
+		return nil, fmt.Errorf("数据库会话已断开")
+	}
+	return s.conn.PrepareContext(ctx, query)
+}
+
+// close 释放连接，修改过会话状态的连接直接关闭，避免被之后的调用方复用
+func (s *session) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	conn := s.conn
+	s.conn = nil
+	if len(s.setup) > 0 {
+		return conn.Discard()
+	}
+	return conn.Close()
+}
+
+// scriptSession 返回脚本固定使用的会话，首次调用时从连接池获取
+func (e *Executor) scriptSession(state *scriptState) (*session, error) {
+	if state.session == nil {
+		s, err := openSession(context.Background(), e.pool, nil)
+		if err != nil {
+			return nil, err
+		}
+		state.session = s
+	}
+	return state.session, nil
+}
+
+// workerQuerier 返回工作协程执行语句的目标
+//
+// 脚本在事务中执行时使用该事务；否则第一个工作协程使用脚本会话，
+// 其余工作协程各自建立会话并重放脚本会话上已执行的设置，返回的函数用于释放会话。
+// 调用前需要通过 scriptSession 建立脚本会话。
+func (e *Executor) workerQuerier(state *scriptState, worker int) (querier, func(), error) {
+	if state.tx != nil {
+		return state.tx, func() {}, nil
+	}
+	if worker == 0 {
+		return state.session, func() {}, nil
+	}
+
+	s, err := openSession(context.Background(), e.pool, state.session.setup)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	return s, func() {
+		if err := s.close(); err != nil {
+			e.logger.Warn("释放数据库连接失败", "error", err)
+		}
+	}, nil
+}
+
+// closeSession 释放脚本固定使用的会话
+func (e *Executor) closeSession(state *scriptState) {
+	if state.session == nil {
+		return
+	}
+	if err := state.session.close(); err != nil {
+		e.logger.Warn("释放数据库连接失败", "error", err)
+	}
+	state.session = nil
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSessionStatement(t *testing.T) {
+	tasks := parseTasks(t, `ALTER SESSION SET CURRENT_SCHEMA = app;
+alter session set nls_date_format = 'YYYY-MM-DD';
+SET ROLE ALL;
+ALTER SYSTEM FLUSH SHARED_POOL;
+ALTER TABLE t ADD (c NUMBER);
+SELECT * FROM t;
+`)
+	var got []bool
+	for _, task := range tasks {
+		got = append(got, isSessionStatement(task))
+	}
+	assert.Equal(t, []bool{true, true, true, false, false, false}, got)
+}
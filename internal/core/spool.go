@@ -0,0 +1,78 @@
+package core
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/formatter"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// spoolPath 返回查询结果文件的路径，结果只输出到控制台时返回空字符串
+//
+// 配置了 spool_dir 时每条查询都写入文件，否则只有标记了 @runner:spool 的查询
+// 写入以脚本命名的目录；在多个数据库上执行时再按数据库名称分子目录。文件名使用 @runner:name 指定的名称，未指定时使用语句序号。
+func (e *Executor) spoolPath(task models.SQLTask) string {
+	if task.Type != models.SQLTypeQuery || (e.config.SpoolDir == "" && !task.Directives.Spool) {
+		return ""
+	}
+	name := task.Directives.Name
+	if name == "" {
+		name = fmt.Sprintf("%04d", task.Index)
+	}
+	dir := e.config.SpoolDir
+	if dir == "" {
+		dir = defaultSpoolDir(e.script)
+	}
+	return filepath.Join(dir, e.spoolSubdir, name+formatter.Extension(e.config.Format))
+}
+
+// defaultSpoolDir 未配置 spool_dir 时结果文件所在的目录，位于当前目录下，
+// 以去掉扩展名的脚本文件名加 _spool 命名，如 deploy.sql 对应 deploy_spool
+func defaultSpoolDir(script string) string {
+	name := strings.Trim(filepath.Base(script), "<>")
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "sql-runner"
+	}
+	return name + "_spool"
+}
+
+// spoolQueryResults 将查询结果按配置的格式写入文件，返回读取的行数以及是否因达到最大行数而截断，
+// 失败时删除不完整的文件
+func (e *Executor) spoolQueryResults(rows *sql.Rows, limits formatter.Limits, path string) (count int64, truncated bool, err error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return 0, false, fmt.Errorf("创建查询结果目录失败: %w", err)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, false, fmt.Errorf("创建查询结果文件失败: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("写入查询结果文件失败: %w", closeErr)
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	w := bufio.NewWriter(file)
+	f, err := e.newFormatter(w)
+	if err != nil {
+		return 0, false, err
+	}
+	if count, truncated, err = limits.WriteRows(f, rows); err != nil {
+		return count, truncated, err
+	}
+	if err = w.Flush(); err != nil {
+		return count, truncated, fmt.Errorf("写入查询结果文件失败: %w", err)
+	}
+	return count, truncated, nil
+}
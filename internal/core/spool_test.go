@@ -0,0 +1,51 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpoolPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		spoolDir string
+		subdir   string
+		format   string
+		sql      string
+		want     string
+	}{
+		{name: "未配置目录", sql: "SELECT 1 FROM dual;", want: ""},
+		{name: "按序号命名", spoolDir: "out", format: "csv", sql: "SELECT 1 FROM dual;", want: filepath.Join("out", "0001.csv")},
+		{name: "按名称命名", spoolDir: "out", format: "json", sql: "-- @runner:name users\nSELECT * FROM users;", want: filepath.Join("out", "users.json")},
+		{name: "默认格式", spoolDir: "out", sql: "SELECT 1 FROM dual;", want: filepath.Join("out", "0001.txt")},
+		{name: "单条语句写入脚本目录", format: "ndjson", sql: "-- @runner:spool\n-- @runner:name users\nSELECT * FROM users;", want: filepath.Join("export_spool", "users.ndjson")},
+		{name: "单条语句指定目录", spoolDir: "out", sql: "-- @runner:spool\nSELECT * FROM users;", want: filepath.Join("out", "0001.txt")},
+		{name: "多数据库按数据库分目录", spoolDir: "out", subdir: "prod-east", format: "csv", sql: "SELECT 1 FROM dual;", want: filepath.Join("out", "prod-east", "0001.csv")},
+		{name: "多数据库单条语句按数据库分目录", subdir: "prod-west", format: "csv", sql: "-- @runner:spool\n-- @runner:name users\nSELECT * FROM users;", want: filepath.Join("export_spool", "prod-west", "users.csv")},
+		{name: "非查询语句", spoolDir: "out", sql: "UPDATE users SET a = 1;", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Executor{config: &config.Config{SpoolDir: tt.spoolDir, Format: tt.format}, script: filepath.Join("scripts", "export.sql"), spoolSubdir: tt.subdir}
+			assert.Equal(t, tt.want, e.spoolPath(parseOne(t, tt.sql)))
+		})
+	}
+}
+
+func TestDefaultSpoolDir(t *testing.T) {
+	tests := map[string]string{
+		"deploy.sql":                         "deploy_spool",
+		filepath.Join("scripts", "v1.2.sql"): "v1.2_spool",
+		"migrate":                            "migrate_spool",
+		"<stdin>":                            "stdin_spool",
+		"<inline>":                           "inline_spool",
+		"":                                   "sql-runner_spool",
+	}
+	for script, want := range tests {
+		assert.Equal(t, want, defaultSpoolDir(script), script)
+	}
+}
@@ -0,0 +1,398 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// sqlplusCommand SQL*Plus 客户端命令定义
+type sqlplusCommand struct {
+	name   string // 完整命令名
+	minLen int    // 允许的最短缩写长度
+}
+
+// sqlplusCommands 支持的 SQL*Plus 命令
+var sqlplusCommands = []sqlplusCommand{
+	{name: "SET", minLen: 3},
+	{name: "PROMPT", minLen: 3},
+	{name: "WHENEVER", minLen: 8},
+	{name: "DEFINE", minLen: 3},
+	{name: "UNDEFINE", minLen: 5},
+	{name: "SPOOL", minLen: 3},
+	{name: "REMARK", minLen: 3},
+	{name: "EXIT", minLen: 4},
+	{name: "QUIT", minLen: 4},
+	// 以下仅影响 SQL*Plus 的显示格式，执行时忽略
+	{name: "COLUMN", minLen: 3},
+	{name: "TTITLE", minLen: 3},
+	{name: "BTITLE", minLen: 3},
+	{name: "BREAK", minLen: 3},
+	{name: "COMPUTE", minLen: 4},
+	{name: "CLEAR", minLen: 2},
+	{name: "PAUSE", minLen: 3},
+}
+
+// ignoredCommands 执行时忽略的命令
+var ignoredCommands = map[string]bool{
+	"COLUMN":  true,
+	"TTITLE":  true,
+	"BTITLE":  true,
+	"BREAK":   true,
+	"COMPUTE": true,
+	"CLEAR":   true,
+	"PAUSE":   true,
+}
+
+// matchCommand 返回单词对应的 SQL*Plus 命令名，不是命令时返回空字符串
+func matchCommand(word string) string {
+	word = strings.ToUpper(word)
+	for _, cmd := range sqlplusCommands {
+		if len(word) >= cmd.minLen && strings.HasPrefix(cmd.name, word) {
+			return cmd.name
+		}
+	}
+	return ""
+}
+
+// isSQLSet 判断 SET 开头的语句是否为 SQL 语句（SET TRANSACTION 等）
+func isSQLSet(rest string) bool {
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "TRANSACTION", "ROLE", "CONSTRAINT", "CONSTRAINTS":
+		return true
+	}
+	return false
+}
+
+// splitCommand 将命令文本拆分为命令名和参数
+func splitCommand(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	end := strings.IndexFunc(text, unicode.IsSpace)
+	if end < 0 {
+		return matchCommand(text), ""
+	}
+	return matchCommand(text[:end]), strings.TrimSpace(text[end:])
+}
+
+// oraCodePattern 匹配错误信息中的 Oracle 错误码
+var oraCodePattern = regexp.MustCompile(`ORA-(\d+)`)
+
+// onErrorAction WHENEVER 指定的错误处理方式
+type onErrorAction struct {
+	exit     bool   // 出错时退出
+	exitCode string // SUCCESS、FAILURE、WARNING、SQL.SQLCODE 或数字
+	rollback bool   // 退出前回滚事务，默认提交
+}
+
+// scriptState SQL*Plus 脚本执行状态，每次执行脚本时创建
+type scriptState struct {
+	console      io.Writer
+	spool        *os.File
+	serverOutput bool // 是否输出 DBMS_OUTPUT 内容，默认开启
+	timing       bool // 是否显示每条语句的执行时间
+	defines      map[string]string
+	sqlError     onErrorAction
+	osError      onErrorAction
+	exited       bool
+	exitCode     int
+	rollback     bool             // 退出时回滚事务
+	session      *session         // 脚本固定使用的数据库会话
+	tx           *sql.Tx          // --tx file/statement 时整个脚本共用的事务
+	txLog        []models.SQLTask // 当前事务中已执行的修改语句，连接中断后在新事务中重放
+	txErr        error            // 事务因连接中断失效的原因，之后的语句不再执行
+}
+
+// newScriptState 创建脚本执行状态
+func newScriptState(console io.Writer) *scriptState {
+	return &scriptState{
+		console:      console,
+		defines:      make(map[string]string),
+		serverOutput: true,
+	}
+}
+
+// writer 返回当前输出目标，SPOOL 打开时同时写入文件
+func (s *scriptState) writer() io.Writer {
+	if s.spool != nil {
+		return io.MultiWriter(s.console, s.spool)
+	}
+	return s.console
+}
+
+// close 释放脚本执行过程中打开的资源
+func (s *scriptState) close() error {
+	if s.spool == nil {
+		return nil
+	}
+	err := s.spool.Close()
+	s.spool = nil
+	return err
+}
+
+// fail 根据 WHENEVER 设置处理错误，返回是否需要退出
+func (s *scriptState) fail(action onErrorAction, err error) bool {
+	if !action.exit {
+		return false
+	}
+	s.exited = true
+	s.exitCode = exitCode(action.exitCode, err)
+	s.rollback = action.rollback
+	return true
+}
+
+// exitCode 将 EXIT 参数转换为退出码
+func exitCode(spec string, err error) int {
+	switch strings.ToUpper(spec) {
+	case "":
+		if err != nil {
+			return 1
+		}
+		return 0
+	case "SUCCESS":
+		return 0
+	case "FAILURE":
+		return 1
+	case "WARNING":
+		return 2
+	case "SQL.SQLCODE":
+		if err == nil {
+			return 0
+		}
+		if match := oraCodePattern.FindStringSubmatch(err.Error()); match != nil {
+			code, _ := strconv.Atoi(match[1])
+			return code
+		}
+		return 1
+	}
+	code, convErr := strconv.Atoi(spec)
+	if convErr != nil {
+		return 1
+	}
+	return code
+}
+
+// parseOnError 解析 EXIT/WHENEVER 的退出参数
+func parseOnError(args []string) (onErrorAction, error) {
+	if len(args) == 0 {
+		return onErrorAction{}, fmt.Errorf("缺少 EXIT 或 CONTINUE")
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "EXIT":
+		action := onErrorAction{exit: true, exitCode: "FAILURE"}
+		for _, arg := range args[1:] {
+			switch strings.ToUpper(arg) {
+			case "COMMIT":
+				action.rollback = false
+			case "ROLLBACK":
+				action.rollback = true
+			default:
+				action.exitCode = arg
+			}
+		}
+		return action, nil
+	case "CONTINUE":
+		return onErrorAction{}, nil
+	}
+	return onErrorAction{}, fmt.Errorf("无效的 WHENEVER 参数: %s", args[0])
+}
+
+// executeCommand 执行 SQL*Plus 客户端命令
+func (e *Executor) executeCommand(task models.SQLTask, state *scriptState) error {
+	name, args := splitCommand(task.SQL)
+	if ignoredCommands[name] {
+		e.logger.Debug("忽略SQL*Plus显示命令", "command", task.SQL, "line", task.LineNum)
+		return nil
+	}
+
+	switch name {
+	case "SET":
+		return e.executeSet(args, state)
+	case "PROMPT":
+		fmt.Fprintln(state.writer(), args)
+		return nil
+	case "WHENEVER":
+		return executeWhenever(args, state)
+	case "DEFINE":
+		return executeDefine(args, state)
+	case "UNDEFINE":
+		for _, name := range strings.Fields(args) {
+			delete(state.defines, strings.ToUpper(name))
+		}
+		return nil
+	case "SPOOL":
+		return executeSpool(args, state)
+	case "EXIT", "QUIT":
+		spec := ""
+		for _, field := range strings.Fields(args) {
+			switch strings.ToUpper(field) {
+			case "COMMIT":
+				state.rollback = false
+			case "ROLLBACK":
+				state.rollback = true
+			default:
+				spec = field
+			}
+		}
+		state.exited = true
+		state.exitCode = exitCode(spec, nil)
+		return nil
+	}
+
+	return fmt.Errorf("不支持的SQL*Plus命令: %s", task.SQL)
+}
+
+// executeSet 处理 SET 命令
+func (e *Executor) executeSet(args string, state *scriptState) error {
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		return fmt.Errorf("SET 命令缺少参数: %s", args)
+	}
+
+	option := strings.ToUpper(fields[0])
+	value := strings.ToUpper(fields[1])
+	switch {
+	case strings.HasPrefix("SERVEROUTPUT", option) && len(option) >= 9:
+		on, err := parseOnOff(value)
+		if err != nil {
+			return err
+		}
+		state.serverOutput = on
+	case strings.HasPrefix("TIMING", option) && len(option) >= 4:
+		on, err := parseOnOff(value)
+		if err != nil {
+			return err
+		}
+		state.timing = on
+	case isDefineOption(option):
+		// 替换变量在解析阶段处理
+	default:
+		e.logger.Warn("忽略不支持的SET选项", "option", args)
+	}
+	return nil
+}
+
+// isDefineOption 判断 SET 选项是否为 DEFINE
+func isDefineOption(option string) bool {
+	option = strings.ToUpper(option)
+	return len(option) >= 3 && strings.HasPrefix("DEFINE", option)
+}
+
+// parseOnOff 解析 ON/OFF 取值
+func parseOnOff(value string) (bool, error) {
+	switch value {
+	case "ON":
+		return true, nil
+	case "OFF":
+		return false, nil
+	}
+	return false, fmt.Errorf("无效的取值: %s，应为 ON 或 OFF", value)
+}
+
+// executeWhenever 处理 WHENEVER SQLERROR/OSERROR 命令
+func executeWhenever(args string, state *scriptState) error {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return fmt.Errorf("WHENEVER 命令缺少参数")
+	}
+
+	action, err := parseOnError(fields[1:])
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "SQLERROR":
+		state.sqlError = action
+	case "OSERROR":
+		state.osError = action
+	default:
+		return fmt.Errorf("无效的 WHENEVER 条件: %s", fields[0])
+	}
+	return nil
+}
+
+// executeDefine 处理 DEFINE 命令，不带取值时打印变量
+func executeDefine(args string, state *scriptState) error {
+	name, value, found := parseDefine(args)
+	if found {
+		if name == "" {
+			return fmt.Errorf("DEFINE 缺少变量名: %s", args)
+		}
+		state.defines[name] = value
+		return nil
+	}
+
+	if name == "" {
+		names := make([]string, 0, len(state.defines))
+		for name := range state.defines {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(state.writer(), "DEFINE %s = \"%s\"\n", name, state.defines[name])
+		}
+		return nil
+	}
+
+	value, ok := state.defines[name]
+	if !ok {
+		return fmt.Errorf("变量 %s 未定义", name)
+	}
+	fmt.Fprintf(state.writer(), "DEFINE %s = \"%s\"\n", name, value)
+	return nil
+}
+
+// executeSpool 处理 SPOOL 命令
+func executeSpool(args string, state *scriptState) error {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return fmt.Errorf("SPOOL 命令缺少参数")
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "OFF", "OUT":
+		return state.close()
+	}
+
+	path := fields[0]
+	if filepath.Ext(path) == "" {
+		path += ".lst"
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if len(fields) > 1 {
+		switch mode := strings.ToUpper(fields[1]); {
+		case strings.HasPrefix("APPEND", mode) && len(mode) >= 3:
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		case strings.HasPrefix("CREATE", mode) && len(mode) >= 3:
+			flags = os.O_CREATE | os.O_WRONLY | os.O_EXCL
+		case strings.HasPrefix("REPLACE", mode) && len(mode) >= 3:
+		default:
+			return fmt.Errorf("无效的 SPOOL 参数: %s", fields[1])
+		}
+	}
+
+	if err := state.close(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开SPOOL文件失败: %w", err)
+	}
+	state.spool = file
+	return nil
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/internal/config"
+	"github.com/iyuangang/oracle-sql-runner/internal/utils"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCommandExecutor 创建仅用于执行客户端命令的执行器，不连接数据库
+func newCommandExecutor(t *testing.T) *Executor {
+	logger, err := utils.NewLogger(filepath.Join(t.TempDir(), "test.log"), "debug", true)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		logger.Close()
+	})
+	return &Executor{logger: logger, config: &config.Config{Mode: config.ModeSerial}}
+}
+
+func commandTask(sql string) models.SQLTask {
+	return models.SQLTask{SQL: sql, Type: models.SQLTypeCommand, LineNum: 1}
+}
+
+func TestMatchCommand(t *testing.T) {
+	tests := map[string]string{
+		"set":      "SET",
+		"PRO":      "PROMPT",
+		"prompt":   "PROMPT",
+		"def":      "DEFINE",
+		"UNDEF":    "UNDEFINE",
+		"spo":      "SPOOL",
+		"REM":      "REMARK",
+		"WHENEVER": "WHENEVER",
+		"col":      "COLUMN",
+		"PR":       "",
+		"SELECT":   "",
+		"RENAME":   "",
+		"COMMENT":  "",
+		"DECLARE":  "",
+		"PROCESS":  "",
+	}
+	for word, want := range tests {
+		assert.Equal(t, want, matchCommand(word), word)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	oraErr := errors.New("ORA-00942: table or view does not exist")
+
+	assert.Equal(t, 0, exitCode("", nil))
+	assert.Equal(t, 1, exitCode("", oraErr))
+	assert.Equal(t, 0, exitCode("SUCCESS", oraErr))
+	assert.Equal(t, 1, exitCode("failure", nil))
+	assert.Equal(t, 2, exitCode("WARNING", nil))
+	assert.Equal(t, 942, exitCode("SQL.SQLCODE", oraErr))
+	assert.Equal(t, 5, exitCode("5", nil))
+	assert.Equal(t, 1, exitCode("abc", nil))
+}
+
+func TestExecuteCommand(t *testing.T) {
+	e := newCommandExecutor(t)
+
+	t.Run("PROMPT", func(t *testing.T) {
+		var out bytes.Buffer
+		state := newScriptState(&out)
+		require.NoError(t, e.executeCommand(commandTask("PROMPT 开始部署 v1"), state))
+		assert.Equal(t, "开始部署 v1\n", out.String())
+	})
+
+	t.Run("SET", func(t *testing.T) {
+		state := newScriptState(&bytes.Buffer{})
+		assert.True(t, state.serverOutput, "默认输出 DBMS_OUTPUT 内容")
+		require.NoError(t, e.executeCommand(commandTask("SET SERVEROUTPUT OFF"), state))
+		assert.False(t, state.serverOutput)
+		require.NoError(t, e.executeCommand(commandTask("SET SERVEROUTPUT ON SIZE UNLIMITED"), state))
+		require.NoError(t, e.executeCommand(commandTask("set timing on"), state))
+		require.NoError(t, e.executeCommand(commandTask("SET PAGESIZE 100"), state))
+		assert.True(t, state.serverOutput)
+		assert.True(t, state.timing)
+
+		assert.Error(t, e.executeCommand(commandTask("SET SERVEROUTPUT MAYBE"), state))
+		assert.Error(t, e.executeCommand(commandTask("SET"), state))
+	})
+
+	t.Run("WHENEVER", func(t *testing.T) {
+		state := newScriptState(&bytes.Buffer{})
+		require.NoError(t, e.executeCommand(commandTask("WHENEVER SQLERROR EXIT SQL.SQLCODE ROLLBACK"), state))
+		assert.Equal(t, onErrorAction{exit: true, exitCode: "SQL.SQLCODE", rollback: true}, state.sqlError)
+
+		require.NoError(t, e.executeCommand(commandTask("WHENEVER OSERROR EXIT"), state))
+		assert.Equal(t, onErrorAction{exit: true, exitCode: "FAILURE"}, state.osError)
+
+		require.NoError(t, e.executeCommand(commandTask("WHENEVER SQLERROR CONTINUE NONE"), state))
+		assert.False(t, state.sqlError.exit)
+
+		assert.Error(t, e.executeCommand(commandTask("WHENEVER SQLERROR STOP"), state))
+		assert.Error(t, e.executeCommand(commandTask("WHENEVER NETERROR EXIT"), state))
+	})
+
+	t.Run("DEFINE", func(t *testing.T) {
+		var out bytes.Buffer
+		state := newScriptState(&out)
+		require.NoError(t, e.executeCommand(commandTask("DEFINE tbs = 'USERS'"), state))
+		require.NoError(t, e.executeCommand(commandTask("DEF schema=app"), state))
+		assert.Equal(t, map[string]string{"TBS": "USERS", "SCHEMA": "app"}, state.defines)
+
+		require.NoError(t, e.executeCommand(commandTask("DEFINE tbs"), state))
+		assert.Equal(t, "DEFINE TBS = \"USERS\"\n", out.String())
+
+		require.NoError(t, e.executeCommand(commandTask("UNDEFINE tbs"), state))
+		assert.Error(t, e.executeCommand(commandTask("DEFINE tbs"), state))
+	})
+
+	t.Run("SPOOL", func(t *testing.T) {
+		var out bytes.Buffer
+		state := newScriptState(&out)
+		path := filepath.Join(t.TempDir(), "deploy")
+
+		require.NoError(t, e.executeCommand(commandTask("SPOOL "+path), state))
+		require.NoError(t, e.executeCommand(commandTask("PROMPT first"), state))
+		require.NoError(t, e.executeCommand(commandTask("SPOOL OFF"), state))
+		require.NoError(t, e.executeCommand(commandTask("PROMPT console only"), state))
+		require.NoError(t, e.executeCommand(commandTask("SPOOL "+path+".lst APPEND"), state))
+		require.NoError(t, e.executeCommand(commandTask("PROMPT second"), state))
+		require.NoError(t, state.close())
+
+		content, err := os.ReadFile(path + ".lst")
+		require.NoError(t, err)
+		assert.Equal(t, "first\nsecond\n", string(content))
+		assert.Equal(t, "first\nconsole only\nsecond\n", out.String())
+
+		assert.Error(t, e.executeCommand(commandTask("SPOOL "+path+".lst CREATE"), state))
+	})
+
+	t.Run("EXIT", func(t *testing.T) {
+		state := newScriptState(&bytes.Buffer{})
+		require.NoError(t, e.executeCommand(commandTask("EXIT 3 COMMIT"), state))
+		assert.True(t, state.exited)
+		assert.Equal(t, 3, state.exitCode)
+		assert.False(t, state.rollback)
+
+		state = newScriptState(&bytes.Buffer{})
+		require.NoError(t, e.executeCommand(commandTask("EXIT ROLLBACK"), state))
+		assert.Equal(t, 0, state.exitCode)
+		assert.True(t, state.rollback)
+	})
+
+	t.Run("忽略显示命令", func(t *testing.T) {
+		state := newScriptState(&bytes.Buffer{})
+		assert.NoError(t, e.executeCommand(commandTask("COLUMN name FORMAT a30"), state))
+	})
+}
+
+func TestExecuteScriptCommands(t *testing.T) {
+	e := newCommandExecutor(t)
+
+	script := "WHENEVER OSERROR EXIT 4\n" +
+		"SPOOL " + filepath.Join(t.TempDir(), "missing", "out.lst") + "\n" +
+		"PROMPT never\n" +
+		"PROMPT never\n"
+	parser := ParseReader(strings.NewReader(script), ParseOptions{Filename: "deploy.sql"})
+	defer parser.Close()
+
+	result := e.executeScript(parser)
+	assert.Equal(t, 1, result.Success)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 2, result.Skipped)
+	assert.True(t, result.Exited)
+	assert.Equal(t, 4, result.ExitCode)
+}
+
+func TestExecuteScriptParseError(t *testing.T) {
+	e := newCommandExecutor(t)
+
+	parser := ParseReader(strings.NewReader("SET TIMING ON\nSELECT 'abc FROM dual;\n"), ParseOptions{Filename: "deploy.sql"})
+	defer parser.Close()
+
+	result := e.executeScript(parser)
+	assert.Equal(t, 1, result.Success)
+	require.Equal(t, 1, result.Failed)
+	assert.Equal(t, "deploy.sql", result.Errors[0].File)
+	assert.Contains(t, result.Errors[0].Message, "第 2 行: 字符串未闭合")
+}
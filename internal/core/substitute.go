@@ -0,0 +1,134 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// substitution SQL*Plus 替换变量（&name、&&name）
+type substitution struct {
+	enabled bool
+	prefix  rune
+	defines map[string]string
+}
+
+// newSubstitution 创建替换变量处理器，变量名不区分大小写
+func newSubstitution(defines map[string]string) *substitution {
+	s := &substitution{
+		enabled: true,
+		prefix:  '&',
+		defines: make(map[string]string, len(defines)),
+	}
+	for name, value := range defines {
+		s.defines[strings.ToUpper(name)] = value
+	}
+	return s
+}
+
+// define 定义变量
+func (s *substitution) define(name, value string) {
+	s.defines[strings.ToUpper(name)] = value
+}
+
+// undefine 删除变量
+func (s *substitution) undefine(name string) {
+	delete(s.defines, strings.ToUpper(name))
+}
+
+// setDefine 处理 SET DEFINE {ON|OFF|c}
+func (s *substitution) setDefine(value string) error {
+	switch strings.ToUpper(value) {
+	case "ON":
+		s.enabled = true
+		s.prefix = '&'
+	case "OFF":
+		s.enabled = false
+	default:
+		value = strings.Trim(value, `'"`)
+		ch, size := utf8.DecodeRuneInString(value)
+		if size == 0 || size != len(value) || isWordPart(ch) || isSpace(ch) {
+			return fmt.Errorf("无效的 SET DEFINE 取值: %s", value)
+		}
+		s.enabled = true
+		s.prefix = ch
+	}
+	return nil
+}
+
+// apply 替换 text 中的变量，line 为 text 起始行号，用于错误提示
+func (s *substitution) apply(text string, line int) (string, error) {
+	if !s.enabled || !strings.ContainsRune(text, s.prefix) {
+		return text, nil
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(text))
+	for i := 0; i < len(text); {
+		ch, size := utf8.DecodeRuneInString(text[i:])
+		if ch == '\n' {
+			line++
+		}
+		if ch != s.prefix {
+			sb.WriteRune(ch)
+			i += size
+			continue
+		}
+
+		// &&name 与 &name 取值方式相同，不会交互式提示输入
+		start := i + size
+		if next, n := utf8.DecodeRuneInString(text[start:]); next == s.prefix {
+			start += n
+		}
+		end := start
+		for end < len(text) {
+			r, n := utf8.DecodeRuneInString(text[end:])
+			if !isWordPart(r) {
+				break
+			}
+			end += n
+		}
+		if end == start {
+			// 前缀后没有变量名时按原样保留
+			sb.WriteString(text[i:end])
+			i = end
+			continue
+		}
+
+		name := strings.ToUpper(text[start:end])
+		value, ok := s.defines[name]
+		if !ok {
+			return "", fmt.Errorf("第 %d 行: 替换变量 %s 未定义", line, name)
+		}
+		sb.WriteString(value)
+
+		// 变量名后的 . 用于连接后续文本，替换时一并去除
+		if end < len(text) && text[end] == '.' {
+			end++
+		}
+		i = end
+	}
+	return sb.String(), nil
+}
+
+// parseDefine 解析 DEFINE 参数，返回变量名、取值以及是否包含赋值
+func parseDefine(args string) (string, string, bool) {
+	name, value, found := strings.Cut(args, "=")
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !found {
+		return name, "", false
+	}
+	return name, unquoteDefine(strings.TrimSpace(value)), true
+}
+
+// unquoteDefine 去除 DEFINE 取值两端的引号
+func unquoteDefine(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '\'' || first == '"') && first == last {
+			quote := string(first)
+			return strings.ReplaceAll(value[1:len(value)-1], quote+quote, quote)
+		}
+	}
+	return value
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubstitution(t *testing.T) {
+	s := newSubstitution(map[string]string{"schema": "app", "Tbs": "USERS"})
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "单个&", input: "SELECT * FROM &schema..users", want: "SELECT * FROM app.users"},
+		{name: "双&", input: "TABLESPACE &&TBS", want: "TABLESPACE USERS"},
+		{name: "字符串中替换", input: "WHERE owner = '&SCHEMA'", want: "WHERE owner = 'app'"},
+		{name: "点号连接", input: "&schema._log", want: "app_log"},
+		{name: "无变量名", input: "SELECT 'a & b' FROM dual", want: "SELECT 'a & b' FROM dual"},
+		{name: "未定义变量", input: "SELECT 1\nFROM &missing", wantErr: "第 11 行: 替换变量 MISSING 未定义"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.apply(tt.input, 10)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("SET DEFINE", func(t *testing.T) {
+		s := newSubstitution(map[string]string{"x": "1"})
+		require.NoError(t, s.setDefine("OFF"))
+		got, err := s.apply("&x &y", 1)
+		require.NoError(t, err)
+		assert.Equal(t, "&x &y", got)
+
+		require.NoError(t, s.setDefine("^"))
+		got, err = s.apply("^x & ^x.", 1)
+		require.NoError(t, err)
+		assert.Equal(t, "1 & 1", got)
+
+		require.NoError(t, s.setDefine("on"))
+		got, err = s.apply("&x", 1)
+		require.NoError(t, err)
+		assert.Equal(t, "1", got)
+
+		assert.Error(t, s.setDefine("ab"))
+		assert.Error(t, s.setDefine("x"))
+	})
+}
+
+func TestParseDefine(t *testing.T) {
+	name, value, ok := parseDefine(" tbs = 'it''s' ")
+	assert.True(t, ok)
+	assert.Equal(t, "TBS", name)
+	assert.Equal(t, "it's", value)
+
+	name, _, ok = parseDefine("tbs")
+	assert.False(t, ok)
+	assert.Equal(t, "TBS", name)
+}
+
+func TestParseSubstitution(t *testing.T) {
+	tmpDir := t.TempDir()
+	parse := func(t *testing.T, content string, defines map[string]string) ([]models.SQLTask, error) {
+		t.Helper()
+		path := filepath.Join(tmpDir, t.Name()+".sql")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return ParseFileWithOptions(path, ParseOptions{Defines: defines})
+	}
+
+	t.Run("外部定义与脚本DEFINE", func(t *testing.T) {
+		tasks, err := parse(t, `DEFINE tbs = USERS
+CREATE TABLE &schema..t ( -- 注释中的 &undefined 不替换
+  id NUMBER) TABLESPACE &tbs;
+PROMPT 部署到 &schema
+UNDEFINE tbs
+DEFINE schema = other
+SELECT '&schema' FROM dual;
+`, map[string]string{"schema": "app"})
+		require.NoError(t, err)
+		require.Len(t, tasks, 6)
+		assert.Equal(t, "CREATE TABLE app.t ( -- 注释中的 &undefined 不替换\n  id NUMBER) TABLESPACE USERS", tasks[1].SQL)
+		assert.Equal(t, "PROMPT 部署到 app", tasks[2].SQL)
+		assert.Equal(t, "SELECT 'other' FROM dual", tasks[5].SQL)
+	})
+
+	t.Run("SET DEFINE OFF", func(t *testing.T) {
+		tasks, err := parse(t, `SET DEFINE OFF
+INSERT INTO t VALUES ('R&D');
+SET DEFINE ON
+SELECT &x FROM dual;
+`, map[string]string{"X": "1"})
+		require.NoError(t, err)
+		require.Len(t, tasks, 4)
+		assert.Equal(t, "INSERT INTO t VALUES ('R&D')", tasks[1].SQL)
+		assert.Equal(t, "SELECT 1 FROM dual", tasks[3].SQL)
+	})
+
+	t.Run("未定义变量", func(t *testing.T) {
+		_, err := parse(t, "SELECT 1 FROM dual;\n\nSELECT *\n  FROM &&missing;\n", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "第 4 行: 替换变量 MISSING 未定义")
+		assert.Contains(t, err.Error(), ".sql")
+	})
+
+	t.Run("已删除的变量", func(t *testing.T) {
+		_, err := parse(t, "DEFINE a = 1\nUNDEFINE a\nSELECT &a FROM dual;\n", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "第 3 行")
+	})
+}
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/iyuangang/oracle-sql-runner/internal/config"
+	"github.com/iyuangang/oracle-sql-runner/pkg/models"
+)
+
+// querier 执行语句的目标，可以是数据库会话或事务
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// savepointName --tx statement 时每条DML执行前设置的保存点
+const savepointName = "sql_runner_stmt"
+
+// usesTx 判断是否需要在事务中执行整个脚本
+func (e *Executor) usesTx() bool {
+	return e.config.Tx == config.TxFile || e.config.Tx == config.TxStatement
+}
+
+// beginTx 在脚本会话上开始事务
+func (e *Executor) beginTx(state *scriptState) (*sql.Tx, error) {
+	s, err := e.scriptSession(state)
+	if err != nil {
+		return nil, err
+	}
+	return s.conn.BeginTx(context.Background(), nil)
+}
+
+// endsTransaction 判断语句执行后当前事务是否已经结束，DDL 和 DCL 会隐式提交
+func endsTransaction(task models.SQLTask) bool {
+	switch task.Type {
+	case models.SQLTypeDDL, models.SQLTypeDCL, models.SQLTypePLSQL:
+		return true
+	case models.SQLTypeTCL:
+		if task.Verb != "COMMIT" && task.Verb != "ROLLBACK" {
+			return false
+		}
+		// ROLLBACK TO SAVEPOINT 不结束事务
+		c := &tokenCursor{tokens: significantTokens(task.SQL)}
+		c.advance()
+		c.accept("WORK")
+		return !c.accept("TO")
+	}
+	return false
+}
+
+// replayableInTx 判断语句能否在连接中断后重新开始的事务中再次执行
+//
+// 匿名块和 CALL 可能在内部提交、使用自治事务，或者调用序列、DBMS_* 包和外部系统等
+// 不随事务回滚的操作，再次执行可能重复生效；标记了 @runner:idempotent 的语句除外。
+func replayableInTx(task models.SQLTask) bool {
+	if task.Directives.Idempotent {
+		return true
+	}
+	switch task.Type {
+	case models.SQLTypeBlock:
+		return false
+	case models.SQLTypeDML:
+		return task.Verb != "CALL"
+	}
+	return true
+}
+
+// unreplayable 返回事务日志中第一条不能重放的语句
+func unreplayable(log []models.SQLTask) (models.SQLTask, bool) {
+	for _, task := range log {
+		if !replayableInTx(task) {
+			return task, true
+		}
+	}
+	return models.SQLTask{}, false
+}
+
+// executeInTx 在脚本事务中执行任务
+//
+// 连接中断时事务中未提交的修改随之丢失：重新建立会话并开始新事务，重放当前事务中
+// 已执行的修改语句后再执行该语句。会结束事务的语句执行期间连接中断时无法确定
+// 之前的修改是否已经提交，不再重试；该语句或事务中已执行的语句不能安全重放时同样
+// 不再重试。不再重试的连接中断使事务失效，之后的语句直接报错，脚本结束时不提交。
+func (e *Executor) executeInTx(ctx context.Context, state *scriptState, task models.SQLTask, output *outputCapture) (taskStats, error) {
+	if state.txErr != nil {
+		return taskStats{}, fmt.Errorf("事务已因连接中断失效，语句未执行: %v", state.txErr)
+	}
+	policy := e.retryPolicy(task)
+	start := time.Now()
+	retries := 0
+
+	for {
+		stats, err := e.executeStatementInTx(ctx, state.tx, task, output)
+		stats.retries = retries
+		if err == nil {
+			if endsTransaction(task) {
+				state.txLog = nil
+			} else if task.Type != models.SQLTypeQuery {
+				state.txLog = append(state.txLog, task)
+			}
+			return stats, nil
+		}
+
+		delay, ok := policy.next(retries+1, time.Since(start), err)
+		lost := connectionLost(err)
+		if !ok {
+			if lost {
+				e.abortTx(state, err)
+			}
+			return stats, err
+		}
+		if lost && endsTransaction(task) {
+			fmt.Fprintf(output, "连接中断，事务可能已经提交，不再重试\n")
+			e.abortTx(state, err)
+			return stats, outcomeUnknown(err)
+		}
+		if lost && !replayableInTx(task) {
+			fmt.Fprintf(output, "连接中断，语句可能已经部分生效，不再重试\n")
+			e.abortTx(state, err)
+			return stats, outcomeUnknown(err)
+		}
+		if blocker, ok := unreplayable(state.txLog); lost && ok {
+			fmt.Fprintf(output, "连接中断，事务中 %s 第 %d 行的语句不能安全重放，不再重试\n", blocker.Filename, blocker.LineNum)
+			e.abortTx(state, err)
+			return stats, outcomeUnknown(err)
+		}
+		retries++
+		e.logger.Warn("事务中的语句执行失败，准备重试",
+			"file", task.Filename,
+			"line", task.LineNum,
+			"retry", retries,
+			"delay", delay,
+			"error", err)
+		if !sleep(ctx, delay) {
+			return stats, err
+		}
+		if lost {
+			if txErr := e.restartTx(state, output); txErr != nil {
+				err = fmt.Errorf("%w; 重新开始事务失败: %v", err, txErr)
+				e.abortTx(state, err)
+				return stats, err
+			}
+		}
+	}
+}
+
+// executeStatementInTx 在事务中执行一次任务，statement 模式下DML失败时回滚到执行前的保存点
+func (e *Executor) executeStatementInTx(ctx context.Context, tx *sql.Tx, task models.SQLTask, output *outputCapture) (taskStats, error) {
+	if e.config.Tx != config.TxStatement || task.Type != models.SQLTypeDML {
+		return e.executeTaskWithOutput(ctx, tx, task, output)
+	}
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepointName); err != nil {
+		return taskStats{}, fmt.Errorf("设置保存点失败: %w", err)
+	}
+	stats, err := e.executeTaskWithOutput(ctx, tx, task, output)
+	if err != nil && !connectionLost(err) {
+		// 语句超时后 ctx 已失效，回滚使用新的上下文
+		if _, rbErr := tx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+savepointName); rbErr != nil {
+			return stats, fmt.Errorf("%w; 回滚到保存点失败: %v", err, rbErr)
+		}
+		fmt.Fprintf(output, "已回滚到语句执行前的保存点\n")
+	}
+	return stats, err
+}
+
+// restartTx 丢弃连接中断的事务，在新连接上开始事务并重放其中已执行的修改语句
+func (e *Executor) restartTx(state *scriptState, output *outputCapture) error {
+	// 连接已经断开，回滚只释放 database/sql 中的事务状态；重新开始失败时保留已结束的事务，
+	// 之后的语句和提交都会报错
+	_ = state.tx.Rollback()
+
+	// 事务需要在脚本结束前保持有效，不使用单条语句的超时上下文
+	ctx := context.Background()
+	if err := state.session.reconnect(ctx); err != nil {
+		return err
+	}
+	tx, err := state.session.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	state.tx = tx
+
+	fmt.Fprintf(output, "连接中断，已重新开始事务并重放 %d 条语句\n", len(state.txLog))
+	for _, task := range state.txLog {
+		if _, err := tx.ExecContext(ctx, task.SQL); err != nil {
+			return fmt.Errorf("重放 %s 第 %d 行的语句失败: %w", task.Filename, task.LineNum, err)
+		}
+	}
+	return nil
+}
+
+// abortTx 连接中断且不再重试时放弃脚本事务
+//
+// 事务中未提交的修改已随连接丢失，之后的语句在 executeInTx 中直接报错，不会在已结束的
+// 事务上执行；脚本会话重新建立，供脚本结束时的清理使用。
+func (e *Executor) abortTx(state *scriptState, err error) {
+	// 连接已经断开，回滚只释放 database/sql 中的事务状态
+	_ = state.tx.Rollback()
+	state.txErr = err
+	state.txLog = nil
+	if state.session != nil {
+		if rcErr := state.session.reconnect(context.Background()); rcErr != nil {
+			e.logger.Error("重新建立数据库会话失败", "error", rcErr)
+		}
+	}
+}
+
+// endTx 结束脚本事务，commit 为 false 时回滚
+func (e *Executor) endTx(state *scriptState, commit bool) error {
+	tx := state.tx
+	if tx == nil {
+		return nil
+	}
+	state.tx = nil
+
+	if state.txErr != nil {
+		// 事务已在 abortTx 中回滚
+		e.logger.Warn("事务已因连接中断失效", "tx", e.config.Tx, "error", state.txErr)
+		if commit {
+			return fmt.Errorf("事务已因连接中断失效，未提交: %v", state.txErr)
+		}
+		return nil
+	}
+	if !commit {
+		if err := tx.Rollback(); err != nil {
+			return fmt.Errorf("回滚事务失败: %w", err)
+		}
+		e.logger.Warn("事务已回滚", "tx", e.config.Tx)
+		fmt.Fprintln(state.writer(), "事务已回滚")
+		return nil
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	e.logger.Info("事务已提交", "tx", e.config.Tx)
+	return nil
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/iyuangang/oracle-sql-runner/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndsTransaction(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{sql: "COMMIT;", want: true},
+		{sql: "COMMIT WORK;", want: true},
+		{sql: "ROLLBACK;", want: true},
+		{sql: "ROLLBACK TO SAVEPOINT before_load;", want: false},
+		{sql: "ROLLBACK WORK TO before_load;", want: false},
+		{sql: "SAVEPOINT before_load;", want: false},
+		{sql: "INSERT INTO t VALUES (1);", want: false},
+		{sql: "SELECT * FROM t;", want: false},
+		{sql: "CREATE TABLE t (id NUMBER);", want: true},
+		{sql: "TRUNCATE TABLE t;", want: true},
+		{sql: "GRANT SELECT ON t TO r;", want: true},
+		{sql: "CREATE OR REPLACE PROCEDURE p AS BEGIN NULL; END;\n/", want: true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, endsTransaction(parseOne(t, tt.sql)), tt.sql)
+	}
+}
+
+func TestReplayableInTx(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{sql: "INSERT INTO t VALUES (1);", want: true},
+		{sql: "UPDATE t SET n = n + 1;", want: true},
+		{sql: "SAVEPOINT before_load;", want: true},
+		{sql: "CALL dbms_stats.gather_table_stats(USER, 'T');", want: false},
+		{sql: "BEGIN pkg.load; END;\n/", want: false},
+		{sql: "DECLARE n NUMBER; BEGIN n := seq.NEXTVAL; END;\n/", want: false},
+		{sql: "-- @runner:idempotent\nBEGIN pkg.reset; END;\n/", want: true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, replayableInTx(parseOne(t, tt.sql)), tt.sql)
+	}
+}
+
+// lostConn 执行任何语句都返回连接中断错误的数据库连接，记录收到的语句
+type lostConn struct {
+	execs []string
+}
+
+func (c *lostConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *lostConn) Driver() driver.Driver                        { return nil }
+func (c *lostConn) Prepare(query string) (driver.Stmt, error) {
+	return &lostStmt{conn: c, query: query}, nil
+}
+func (c *lostConn) Close() error              { return nil }
+func (c *lostConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *lostConn) Commit() error             { return nil }
+func (c *lostConn) Rollback() error           { return nil }
+
+type lostStmt struct {
+	conn  *lostConn
+	query string
+}
+
+func (s *lostStmt) Close() error  { return nil }
+func (s *lostStmt) NumInput() int { return -1 }
+
+func (s *lostStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.conn.execs = append(s.conn.execs, s.query)
+	return nil, errors.New("ORA-03113: end-of-file on communication channel")
+}
+
+func (s *lostStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("ORA-03113: end-of-file on communication channel")
+}
+
+func TestExecuteInTxUnreplayable(t *testing.T) {
+	e := newCommandExecutor(t)
+	e.config.Tx = config.TxFile
+
+	tests := []struct {
+		name string
+		log  []string
+		sql  string
+	}{
+		{name: "事务中已执行匿名块", log: []string{"INSERT INTO t VALUES (1);", "BEGIN pkg.load; END;\n/"}, sql: "INSERT INTO t VALUES (2);"},
+		{name: "事务中已执行CALL", log: []string{"CALL pkg.load();"}, sql: "UPDATE t SET n = 1;"},
+		{name: "匿名块执行期间中断", sql: "BEGIN pkg.load; END;\n/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &lostConn{}
+			db := sql.OpenDB(conn)
+			defer db.Close()
+			tx, err := db.Begin()
+			require.NoError(t, err)
+
+			state := newScriptState(&bytes.Buffer{})
+			state.tx = tx
+			for _, s := range tt.log {
+				state.txLog = append(state.txLog, parseOne(t, s))
+			}
+
+			output := &outputCapture{}
+			_, err = e.executeInTx(context.Background(), state, parseOne(t, tt.sql), output)
+			assert.ErrorIs(t, err, errOutcomeUnknown)
+			assert.Equal(t, []string{parseOne(t, tt.sql).SQL}, conn.execs, "不重新开始事务，也不重放日志")
+			assert.Contains(t, output.buf.String(), "不再重试")
+
+			// 事务已失效，之后的语句不再执行，脚本结束时不提交
+			_, err = e.executeInTx(context.Background(), state, parseOne(t, "INSERT INTO t VALUES (3);"), output)
+			assert.ErrorContains(t, err, "事务已因连接中断失效")
+			assert.Len(t, conn.execs, 1)
+			assert.ErrorContains(t, e.endTx(state, true), "未提交")
+			assert.Nil(t, state.tx)
+		})
+	}
+}
@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -50,44 +52,43 @@ func NewPool(cfg *config.DatabaseConfig, logger *utils.Logger) (*Pool, error) {
 func (p *Pool) ExecContext(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
 	start := time.Now()
 	result, err := p.db.ExecContext(ctx, sql, args...)
-	duration := time.Since(start)
-
-	if err != nil {
-		p.metrics.AddQuery(duration, false)
-		p.logger.Error("SQL执行失败",
-			"sql", sql,
-			"duration", duration,
-			"error", err)
-		return nil, err
-	}
-
-	p.metrics.AddQuery(duration, true)
-	p.logger.Debug("SQL执行成功",
-		"sql", sql,
-		"duration", duration)
-	return result, nil
+	p.observe("SQL执行", sql, start, err)
+	return result, err
 }
 
 // QueryContext 执行查询
 func (p *Pool) QueryContext(ctx context.Context, sql string, args ...interface{}) (*sql.Rows, error) {
 	start := time.Now()
 	rows, err := p.db.QueryContext(ctx, sql, args...)
-	duration := time.Since(start)
+	p.observe("查询执行", sql, start, err)
+	return rows, err
+}
 
+// observe 记录语句的执行耗时和结果
+func (p *Pool) observe(action, sql string, start time.Time, err error) {
+	duration := time.Since(start)
 	if err != nil {
 		p.metrics.AddQuery(duration, false)
-		p.logger.Error("查询执行失败",
+		p.logger.Error(action+"失败",
 			"sql", sql,
 			"duration", duration,
 			"error", err)
-		return nil, err
+		return
 	}
 
 	p.metrics.AddQuery(duration, true)
-	p.logger.Debug("查询执行成功",
+	p.logger.Debug(action+"成功",
 		"sql", sql,
 		"duration", duration)
-	return rows, nil
+}
+
+// Conn 从连接池中取出一个连接，之后在其上执行的语句共享同一数据库会话
+func (p *Pool) Conn(ctx context.Context) (*Conn, error) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	return &Conn{conn: conn, pool: p}, nil
 }
 
 // Begin 开始事务
@@ -95,6 +96,55 @@ func (p *Pool) Begin() (*sql.Tx, error) {
 	return p.db.Begin()
 }
 
+// Conn 固定的单个数据库会话，ALTER SESSION、临时表和包状态在其上执行的语句之间保持
+type Conn struct {
+	conn *sql.Conn
+	pool *Pool
+}
+
+// ExecContext 在会话上执行SQL语句
+func (c *Conn) ExecContext(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := c.conn.ExecContext(ctx, sql, args...)
+	c.pool.observe("SQL执行", sql, start, err)
+	return result, err
+}
+
+// QueryContext 在会话上执行查询
+func (c *Conn) QueryContext(ctx context.Context, sql string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := c.conn.QueryContext(ctx, sql, args...)
+	c.pool.observe("查询执行", sql, start, err)
+	return rows, err
+}
+
+// PrepareContext 在会话上准备语句
+func (c *Conn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.conn.PrepareContext(ctx, query)
+}
+
+// BeginTx 在会话上开始事务
+func (c *Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.conn.BeginTx(ctx, opts)
+}
+
+// Close 将连接归还连接池
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Discard 关闭底层连接而不归还连接池，用于会话状态已被修改、不应被其他调用方复用的连接
+func (c *Conn) Discard() error {
+	// Raw 返回 driver.ErrBadConn 时 database/sql 会关闭该连接
+	err := c.conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	if errors.Is(err, driver.ErrBadConn) {
+		return nil
+	}
+	return err
+}
+
 // Close 关闭连接池
 func (p *Pool) Close() error {
 	return p.db.Close()
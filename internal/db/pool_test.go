@@ -247,6 +247,34 @@ func TestPoolQueryContext(t *testing.T) {
 	}
 }
 
+func TestPoolConn(t *testing.T) {
+	cfg, logger := setupTestEnv(t)
+	pool, err := NewPool(cfg, logger)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+
+	// 会话设置在同一连接上保持
+	_, err = conn.ExecContext(ctx, "ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY'")
+	require.NoError(t, err)
+	rows, err := conn.QueryContext(ctx, "SELECT TO_CHAR(DATE '2024-01-01') FROM DUAL")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	var value string
+	require.NoError(t, rows.Scan(&value))
+	rows.Close()
+	assert.Equal(t, "2024", value)
+
+	// 丢弃的连接不再放回连接池
+	require.NoError(t, conn.Discard())
+	assert.Equal(t, 0, pool.Stats().InUse)
+	_, err = conn.ExecContext(ctx, "SELECT 1 FROM DUAL")
+	assert.Error(t, err)
+}
+
 func TestPoolTransaction(t *testing.T) {
 	cfg, logger := setupTestEnv(t)
 	pool, err := NewPool(cfg, logger)
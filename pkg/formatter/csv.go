@@ -0,0 +1,42 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// CSV 按 RFC 4180 输出，首行为列名，NULL 输出为空字段
+type CSV struct {
+	base
+	w      *csv.Writer
+	record []string
+}
+
+// NewCSV 创建 CSV 格式化器
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{base: newBase(), w: csv.NewWriter(w)}
+}
+
+func (c *CSV) Begin(columns []Column) error {
+	c.columns = columns
+	c.record = make([]string, len(columns))
+	for i, col := range columns {
+		c.record[i] = col.Name
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *CSV) Row(values []interface{}) error {
+	for i, v := range values {
+		c.record[i] = ""
+		if !c.null(i, v) {
+			c.record[i] = c.Renderer.Text(c.columns[i], v)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *CSV) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,156 @@
+// Package formatter 将查询结果集格式化为表格、CSV、JSON 等文本
+package formatter
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 输出格式
+const (
+	FormatTable    = "table"    // 按显示宽度对齐的表格
+	FormatVertical = "vertical" // 每列一行，适合列较多的结果
+	FormatCSV      = "csv"
+	FormatJSON     = "json"   // 每个结果集输出一个对象数组
+	FormatNDJSON   = "ndjson" // 每行输出一个对象
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Formats 支持的输出格式
+var Formats = []string{FormatTable, FormatVertical, FormatCSV, FormatJSON, FormatNDJSON, FormatMarkdown, FormatHTML}
+
+// Column 结果集中的列
+type Column struct {
+	Name string
+	Type string // 数据库类型名，如 NUMBER、VARCHAR2，未知时为空
+}
+
+// ResultFormatter 结果集格式化器
+//
+// 每个结果集依次调用 Begin、Row 和 End，一个格式化器只用于一个结果集。
+// Row 返回后 values 会被复用，实现不能保留该切片。
+type ResultFormatter interface {
+	Begin(columns []Column) error
+	Row(values []interface{}) error
+	End() error
+}
+
+// New 创建写入 w 的格式化器，format 为空时使用 table
+func New(format string, w io.Writer) (ResultFormatter, error) {
+	return NewWithRenderer(format, w, DefaultRenderer())
+}
+
+// NewWithRenderer 创建使用指定渲染器的格式化器
+func NewWithRenderer(format string, w io.Writer, r Renderer) (ResultFormatter, error) {
+	var b *base
+	var f ResultFormatter
+	switch format {
+	case "", FormatTable:
+		t := NewTable(w)
+		b, f = &t.base, t
+	case FormatVertical:
+		v := NewVertical(w)
+		b, f = &v.base, v
+	case FormatCSV:
+		c := NewCSV(w)
+		b, f = &c.base, c
+	case FormatJSON:
+		j := NewJSON(w)
+		b, f = &j.base, j
+	case FormatNDJSON:
+		j := NewNDJSON(w)
+		b, f = &j.base, j
+	case FormatMarkdown:
+		m := NewMarkdown(w)
+		b, f = &m.base, m
+	case FormatHTML:
+		h := NewHTML(w)
+		b, f = &h.base, h
+	default:
+		return nil, fmt.Errorf("无效的输出格式: %s，可选值为 %s", format, strings.Join(Formats, "、"))
+	}
+	b.Renderer = r
+	return f, nil
+}
+
+// Extension 返回输出格式对应的文件扩展名
+func Extension(format string) string {
+	switch format {
+	case FormatCSV:
+		return ".csv"
+	case FormatJSON:
+		return ".json"
+	case FormatNDJSON:
+		return ".ndjson"
+	case FormatMarkdown:
+		return ".md"
+	case FormatHTML:
+		return ".html"
+	}
+	return ".txt"
+}
+
+// Limits 读取结果集时的限制，零值表示不限制
+type Limits struct {
+	MaxRows  int64 // 最多输出的行数
+	LOBBytes int   // 每个 LOB 值最多读取的字节数
+}
+
+// WriteRows 读取结果集的全部行并交给格式化器输出，返回读取的行数
+func WriteRows(f ResultFormatter, rows *sql.Rows) (int64, error) {
+	count, _, err := Limits{}.WriteRows(f, rows)
+	return count, err
+}
+
+// WriteRows 按限制读取结果集并交给格式化器输出，返回输出的行数，
+// truncated 表示达到 MaxRows 后还有未读取的行
+//
+// 以 io.Reader 返回的 LOB（如 godror.LobAsReader）按 LOBBytes 读取后转换为 LOB，
+// 不会完整读入内存。
+func (l Limits) WriteRows(f ResultFormatter, rows *sql.Rows) (count int64, truncated bool, err error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, false, fmt.Errorf("获取列信息失败: %w", err)
+	}
+	columns := make([]Column, len(types))
+	for i, t := range types {
+		columns[i] = Column{Name: t.Name(), Type: t.DatabaseTypeName()}
+	}
+	if err := f.Begin(columns); err != nil {
+		return 0, false, err
+	}
+
+	values := make([]interface{}, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if l.MaxRows > 0 && count >= l.MaxRows {
+			truncated = true
+			break
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return count, false, fmt.Errorf("扫描行数据失败: %w", err)
+		}
+		for i, v := range values {
+			if r, ok := v.(io.Reader); ok {
+				if values[i], err = readLOB(r, l.LOBBytes); err != nil {
+					return count, false, err
+				}
+			}
+		}
+		if err := f.Row(values); err != nil {
+			return count, false, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, truncated, err
+	}
+	return count, truncated, f.End()
+}
@@ -0,0 +1,229 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godror/godror"
+)
+
+// render 使用指定格式输出固定的测试结果集
+func render(t *testing.T, format string, columns []Column, rows [][]interface{}) string {
+	t.Helper()
+	var buf bytes.Buffer
+	f, err := New(format, &buf)
+	if err != nil {
+		t.Fatalf("New(%q) 返回错误: %v", format, err)
+	}
+	if err := f.Begin(columns); err != nil {
+		t.Fatalf("Begin 返回错误: %v", err)
+	}
+	for _, row := range rows {
+		if err := f.Row(row); err != nil {
+			t.Fatalf("Row 返回错误: %v", err)
+		}
+	}
+	if err := f.End(); err != nil {
+		t.Fatalf("End 返回错误: %v", err)
+	}
+	return buf.String()
+}
+
+func TestFormatters(t *testing.T) {
+	columns := []Column{{Name: "ID", Type: "NUMBER"}, {Name: "NAME", Type: "VARCHAR2"}, {Name: "NOTE", Type: "VARCHAR2"}}
+	rows := [][]interface{}{
+		{godror.Number("1"), "张三", nil},
+		{godror.Number("12.5"), "a,b", "say \"hi\"\n<b>|"},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatTable,
+			want: "  ID | NAME | NOTE\n" +
+				"-----+------+--------------\n" +
+				"   1 | 张三 | NULL\n" +
+				"12.5 | a,b  | say \"hi\" <b>|\n" +
+				"\n共返回 2 行数据\n",
+		},
+		{
+			format: FormatCSV,
+			want:   "ID,NAME,NOTE\n1,张三,\n12.5,\"a,b\",\"say \"\"hi\"\"\n<b>|\"\n",
+		},
+		{
+			format: FormatJSON,
+			want: "[\n" +
+				"  {\"ID\":1,\"NAME\":\"张三\",\"NOTE\":null},\n" +
+				"  {\"ID\":12.5,\"NAME\":\"a,b\",\"NOTE\":\"say \\\"hi\\\"\\n\\u003cb\\u003e|\"}\n" +
+				"]\n",
+		},
+		{
+			format: FormatNDJSON,
+			want: "{\"ID\":1,\"NAME\":\"张三\",\"NOTE\":null}\n" +
+				"{\"ID\":12.5,\"NAME\":\"a,b\",\"NOTE\":\"say \\\"hi\\\"\\n\\u003cb\\u003e|\"}\n",
+		},
+		{
+			format: FormatMarkdown,
+			want: "| ID | NAME | NOTE |\n" +
+				"| --: | --- | --- |\n" +
+				"| 1 | 张三 | NULL |\n" +
+				"| 12.5 | a,b | say \"hi\"<br><b>\\| |\n",
+		},
+		{
+			format: FormatHTML,
+			want: "<table>\n<thead>\n<tr><th>ID</th><th>NAME</th><th>NOTE</th></tr>\n</thead>\n<tbody>\n" +
+				"<tr><td>1</td><td>张三</td><td class=\"null\">NULL</td></tr>\n" +
+				"<tr><td>12.5</td><td>a,b</td><td>say &#34;hi&#34;\n&lt;b&gt;|</td></tr>\n" +
+				"</tbody>\n</table>\n",
+		},
+		{
+			format: FormatVertical,
+			want: "*************************** 1. 行 ***************************\n" +
+				"  ID: 1\n" +
+				"NAME: 张三\n" +
+				"NOTE: NULL\n" +
+				"*************************** 2. 行 ***************************\n" +
+				"  ID: 12.5\n" +
+				"NAME: a,b\n" +
+				"NOTE: say \"hi\"\n<b>|\n" +
+				"\n共返回 2 行数据\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if got := render(t, tt.format, columns, rows); got != tt.want {
+				t.Errorf("输出不正确:\n got: %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormattersEmpty(t *testing.T) {
+	columns := []Column{{Name: "ID", Type: "NUMBER"}}
+	tests := map[string]string{
+		FormatTable:  "ID\n--\n\n共返回 0 行数据\n",
+		FormatCSV:    "ID\n",
+		FormatJSON:   "[]\n",
+		FormatNDJSON: "",
+	}
+	for format, want := range tests {
+		if got := render(t, format, columns, nil); got != want {
+			t.Errorf("%s 空结果集输出不正确:\n got: %q\nwant: %q", format, got, want)
+		}
+	}
+}
+
+func TestTableStreaming(t *testing.T) {
+	var buf bytes.Buffer
+	table := NewTable(&buf)
+	if err := table.Begin([]Column{{Name: "ID", Type: "NUMBER"}, {Name: "NAME", Type: "VARCHAR2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < tableSampleRows; i++ {
+		if err := table.Row([]interface{}{godror.Number(fmt.Sprint(i)), "a"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("列宽确定前不应输出: %q", buf.String())
+	}
+
+	// 缓存的行数达到上限后输出表头和已缓存的行
+	if err := table.Row([]interface{}{godror.Number("100"), "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), " ID | NAME\n----+-----\n  1 | a\n") || !strings.HasSuffix(buf.String(), "\n100 | b\n") {
+		t.Fatalf("表头或缓存的行输出不正确:\n%s", buf.String())
+	}
+	if table.rows != nil {
+		t.Error("输出后不应继续缓存行")
+	}
+
+	// 之后的行立即输出，超出列宽的值不截断
+	buf.Reset()
+	if err := table.Row([]interface{}{godror.Number("12345"), "超过列宽"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "12345 | 超过列宽\n"; got != want {
+		t.Errorf("流式输出不正确:\n got: %q\nwant: %q", got, want)
+	}
+
+	buf.Reset()
+	if err := table.End(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "\n共返回 101 行数据\n"; got != want {
+		t.Errorf("汇总行不正确:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, format := range append(Formats, "") {
+		if _, err := New(format, &bytes.Buffer{}); err != nil {
+			t.Errorf("New(%q) 返回错误: %v", format, err)
+		}
+	}
+	if _, err := New("xml", &bytes.Buffer{}); err == nil {
+		t.Error("New(\"xml\") 应该返回错误")
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		typ   string
+		value interface{}
+		want  string
+	}{
+		{"NUMBER", nil, "null"},
+		{"VARCHAR2", "", "null"},
+		{"", "", `""`},
+		{"BINARY_INTEGER", int64(42), "42"},
+		{"BINARY_DOUBLE", 3.5, "3.5"},
+		{"BINARY_DOUBLE", math.Inf(1), `"+Inf"`},
+		{"BOOLEAN", true, "true"},
+		{"NUMBER", godror.Number("12345678901234567890.123"), "12345678901234567890.123"},
+		{"NUMBER", godror.Number("bad"), `"bad"`},
+		{"RAW", []byte{0xca, 0xfe}, `"CAFE"`},
+		{"TIMESTAMP", ts, `"2024-05-01T08:30:00Z"`},
+		{"INTERVAL DAY TO SECOND", 90 * time.Minute, `"+0 01:30:00"`},
+	}
+	for _, tt := range tests {
+		j := NewJSON(nil)
+		j.columns = []Column{{Name: "V", Type: tt.typ}}
+		got, err := j.value(0, tt.value)
+		if err != nil {
+			t.Fatalf("value(%v) 返回错误: %v", tt.value, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s value(%v) = %s, want %s", tt.typ, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := map[string]int{
+		"":          0,
+		"abc":       3,
+		"张三":        4,
+		"ｈｉ":        4,
+		"テスト":       6,
+		"한국":        4,
+		"é":        1,
+		"a\tb":      2,
+		"混合mixed字符": 13,
+	}
+	for s, want := range tests {
+		if got := DisplayWidth(s); got != want {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
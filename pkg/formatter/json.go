@@ -0,0 +1,115 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/godror/godror"
+)
+
+// JSON 将结果集输出为对象数组，每行一个对象，键的顺序与列顺序一致
+type JSON struct {
+	base
+	w       io.Writer
+	keys    [][]byte
+	buf     bytes.Buffer
+	lines   bool // 为 true 时按 NDJSON 每行输出一个对象
+	started bool
+}
+
+// NewJSON 创建 JSON 格式化器
+func NewJSON(w io.Writer) *JSON {
+	return &JSON{base: newBase(), w: w}
+}
+
+// NewNDJSON 创建 NDJSON 格式化器，每行一个 JSON 对象，适合流式处理
+func NewNDJSON(w io.Writer) *JSON {
+	return &JSON{base: newBase(), w: w, lines: true}
+}
+
+func (j *JSON) Begin(columns []Column) error {
+	j.columns = columns
+	j.keys = make([][]byte, len(columns))
+	for i, col := range columns {
+		key, err := json.Marshal(col.Name)
+		if err != nil {
+			return err
+		}
+		j.keys[i] = key
+	}
+	if j.lines {
+		return nil
+	}
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *JSON) Row(values []interface{}) error {
+	j.buf.Reset()
+	switch {
+	case j.lines:
+	case j.started:
+		j.buf.WriteString(",\n  ")
+	default:
+		j.buf.WriteString("\n  ")
+	}
+	j.started = true
+
+	j.buf.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			j.buf.WriteByte(',')
+		}
+		j.buf.Write(j.keys[i])
+		j.buf.WriteByte(':')
+		value, err := j.value(i, v)
+		if err != nil {
+			return err
+		}
+		j.buf.Write(value)
+	}
+	j.buf.WriteByte('}')
+	if j.lines {
+		j.buf.WriteByte('\n')
+	}
+	_, err := j.w.Write(j.buf.Bytes())
+	return err
+}
+
+func (j *JSON) End() error {
+	if j.lines {
+		return nil
+	}
+	end := "]\n"
+	if j.started {
+		end = "\n]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// value 返回第 i 列值的 JSON 编码，数值保持原有精度，日期时间使用 RFC 3339，
+// 其他类型按渲染后的文本输出
+func (j *JSON) value(i int, v interface{}) ([]byte, error) {
+	if j.null(i, v) {
+		return []byte("null"), nil
+	}
+	switch v := v.(type) {
+	case godror.Number:
+		if json.Valid([]byte(v)) {
+			return []byte(v), nil
+		}
+	case int, int32, int64, uint, uint32, uint64, bool:
+		return json.Marshal(v)
+	case float32, float64:
+		// NaN 和 Inf 无法表示为 JSON 数值，按文本输出
+		if data, err := json.Marshal(v); err == nil {
+			return data, nil
+		}
+	case time.Time:
+		return json.Marshal(v.Format(time.RFC3339Nano))
+	}
+	return json.Marshal(j.Renderer.Text(j.columns[i], v))
+}
@@ -0,0 +1,56 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/godror/godror"
+)
+
+// truncatedSuffix 超过字节数限制的 LOB 在文本中的后缀
+const truncatedSuffix = "...(已截断)"
+
+// LOB 按字节数限制读取的 CLOB 或 BLOB 内容
+type LOB struct {
+	Data      []byte
+	IsClob    bool
+	Truncated bool // 内容超过限制，只读取了前面的部分
+}
+
+// readLOB 从 LOB 中最多读取 limit 字节，limit 为 0 时读取全部内容
+func readLOB(r io.Reader, limit int) (LOB, error) {
+	var lob LOB
+	if l, ok := r.(*godror.Lob); ok {
+		lob.IsClob = l.IsClob
+	}
+	if limit > 0 {
+		r = io.LimitReader(r, int64(limit)+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return lob, fmt.Errorf("读取LOB失败: %w", err)
+	}
+	if limit > 0 && len(data) > limit {
+		data = data[:limit]
+		lob.Truncated = true
+		if lob.IsClob {
+			data = trimPartialRune(data)
+		}
+	}
+	lob.Data = data
+	return lob, nil
+}
+
+// trimPartialRune 去掉截断后末尾不完整的 UTF-8 字符
+func trimPartialRune(data []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			break
+		}
+	}
+	return data
+}
@@ -0,0 +1,102 @@
+package formatter
+
+import (
+	"html"
+	"io"
+	"strings"
+)
+
+// markdownReplacer 转义 Markdown 表格单元格中的竖线和换行
+var markdownReplacer = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// Markdown 输出 GitHub 风格的 Markdown 表格
+type Markdown struct {
+	base
+	w     io.Writer
+	cells []string
+}
+
+// NewMarkdown 创建 Markdown 格式化器
+func NewMarkdown(w io.Writer) *Markdown {
+	return &Markdown{base: newBase(), w: w}
+}
+
+func (m *Markdown) Begin(columns []Column) error {
+	m.columns = columns
+	m.cells = make([]string, len(columns))
+	rule := make([]string, len(columns))
+	for i, col := range columns {
+		m.cells[i] = markdownReplacer.Replace(col.Name)
+		rule[i] = "---"
+		if numericTypes[col.Type] {
+			rule[i] = "--:"
+		}
+	}
+	if err := m.writeRow(m.cells); err != nil {
+		return err
+	}
+	return m.writeRow(rule)
+}
+
+func (m *Markdown) Row(values []interface{}) error {
+	for i, v := range values {
+		m.cells[i] = markdownReplacer.Replace(m.text(i, v))
+	}
+	return m.writeRow(m.cells)
+}
+
+func (m *Markdown) End() error {
+	return nil
+}
+
+func (m *Markdown) writeRow(cells []string) error {
+	_, err := io.WriteString(m.w, "| "+strings.Join(cells, " | ")+" |\n")
+	return err
+}
+
+// HTML 输出 HTML 表格片段，NULL 单元格带 class="null"
+type HTML struct {
+	base
+	w  io.Writer
+	sb strings.Builder
+}
+
+// NewHTML 创建 HTML 格式化器
+func NewHTML(w io.Writer) *HTML {
+	return &HTML{base: newBase(), w: w}
+}
+
+func (h *HTML) Begin(columns []Column) error {
+	h.columns = columns
+	h.sb.Reset()
+	h.sb.WriteString("<table>\n<thead>\n<tr>")
+	for _, col := range columns {
+		h.sb.WriteString("<th>" + html.EscapeString(col.Name) + "</th>")
+	}
+	h.sb.WriteString("</tr>\n</thead>\n<tbody>\n")
+	return h.flush()
+}
+
+func (h *HTML) Row(values []interface{}) error {
+	h.sb.WriteString("<tr>")
+	for i, v := range values {
+		if h.null(i, v) {
+			h.sb.WriteString(`<td class="null">` + html.EscapeString(h.Renderer.Null) + "</td>")
+		} else {
+			h.sb.WriteString("<td>" + html.EscapeString(h.Renderer.Text(h.columns[i], v)) + "</td>")
+		}
+	}
+	h.sb.WriteString("</tr>\n")
+	return h.flush()
+}
+
+func (h *HTML) End() error {
+	h.sb.WriteString("</tbody>\n</table>\n")
+	return h.flush()
+}
+
+func (h *HTML) flush() error {
+	_, err := io.WriteString(h.w, h.sb.String())
+	h.sb.Reset()
+	return err
+}
@@ -0,0 +1,198 @@
+package formatter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godror/godror"
+)
+
+// 默认的显示格式，使用 Go 时间格式，小数秒末尾的 0 会被省略
+const (
+	DefaultDateFormat        = "2006-01-02 15:04:05"
+	DefaultTimestampFormat   = "2006-01-02 15:04:05.999999999"
+	DefaultTimestampTZFormat = "2006-01-02 15:04:05.999999999 -07:00"
+	DefaultNull              = "NULL"
+)
+
+// Renderer 根据列的数据库类型将值转换为文本
+type Renderer struct {
+	DateFormat        string // DATE 的显示格式，为空时使用 DefaultDateFormat
+	TimestampFormat   string // TIMESTAMP 的显示格式，为空时使用 DefaultTimestampFormat
+	TimestampTZFormat string // TIMESTAMP WITH [LOCAL] TIME ZONE 的显示格式，为空时使用 DefaultTimestampTZFormat
+	Null              string // 表格、纵向、Markdown 和 HTML 格式中 NULL 的显示
+}
+
+// DefaultRenderer 返回使用默认格式的渲染器
+func DefaultRenderer() Renderer {
+	return Renderer{Null: DefaultNull}
+}
+
+// characterTypes 字符类型，驱动对这些类型的 NULL 返回空字符串
+var characterTypes = map[string]bool{
+	"VARCHAR2":  true,
+	"NVARCHAR2": true,
+	"CHAR":      true,
+	"NCHAR":     true,
+	"LONG":      true,
+	"CLOB":      true,
+	"NCLOB":     true,
+	"XMLTYPE":   true,
+}
+
+// binaryTypes 以十六进制显示的二进制类型
+var binaryTypes = map[string]bool{
+	"RAW":      true,
+	"LONG RAW": true,
+	"BLOB":     true,
+	"BFILE":    true,
+}
+
+// numericTypes 右对齐显示的数值类型
+var numericTypes = map[string]bool{
+	"NUMBER":         true,
+	"FLOAT":          true,
+	"DOUBLE":         true,
+	"INTEGER":        true,
+	"BINARY_INTEGER": true,
+	"BINARY_FLOAT":   true,
+	"BINARY_DOUBLE":  true,
+}
+
+// IsNull 判断值是否为 NULL，Oracle 不区分空字符串和 NULL
+func (r Renderer) IsNull(col Column, v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == "" && characterTypes[col.Type]
+	}
+	return false
+}
+
+// Text 返回非 NULL 值的文本形式
+func (r Renderer) Text(col Column, v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		if col.Type == "INTERVAL YEAR TO MONTH" {
+			return formatIntervalYM(v)
+		}
+		return v
+	case godror.Number:
+		return string(v)
+	case []byte:
+		if binaryTypes[col.Type] {
+			return strings.ToUpper(hex.EncodeToString(v))
+		}
+		return string(v)
+	case LOB:
+		text := string(v.Data)
+		if !v.IsClob && binaryTypes[col.Type] {
+			text = strings.ToUpper(hex.EncodeToString(v.Data))
+		}
+		if v.Truncated {
+			text += truncatedSuffix
+		}
+		return text
+	case time.Time:
+		return v.Format(r.timeFormat(col.Type))
+	case time.Duration:
+		return formatIntervalDS(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// timeFormat 返回日期时间类型的显示格式
+func (r Renderer) timeFormat(typ string) string {
+	switch typ {
+	case "DATE":
+		if r.DateFormat != "" {
+			return r.DateFormat
+		}
+		return DefaultDateFormat
+	case "TIMESTAMP WITH TIME ZONE", "TIMESTAMP WITH LOCAL TIME ZONE":
+		if r.TimestampTZFormat != "" {
+			return r.TimestampTZFormat
+		}
+		return DefaultTimestampTZFormat
+	}
+	if r.TimestampFormat != "" {
+		return r.TimestampFormat
+	}
+	return DefaultTimestampFormat
+}
+
+// formatIntervalDS 按 Oracle 的格式显示 INTERVAL DAY TO SECOND，如 +1 02:03:04.5
+func formatIntervalDS(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	seconds := d / time.Second
+	nanos := d - seconds*time.Second
+
+	text := fmt.Sprintf("%s%d %02d:%02d:%02d", sign, days, hours, minutes, seconds)
+	if nanos > 0 {
+		text += "." + strings.TrimRight(fmt.Sprintf("%09d", nanos), "0")
+	}
+	return text
+}
+
+// formatIntervalYM 按 Oracle 的格式显示 INTERVAL YEAR TO MONTH，如 +1-02
+//
+// 驱动返回的格式为“年-月”，负数时年和月都带负号，如 -1--2。
+func formatIntervalYM(v string) string {
+	var years, months int
+	if _, err := fmt.Sscanf(v, "%d-%d", &years, &months); err != nil {
+		return v
+	}
+	sign := "+"
+	if years < 0 || months < 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s%d-%02d", sign, abs(years), abs(months))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// base 各格式化器共用的列信息和值渲染
+type base struct {
+	Renderer Renderer // 值的显示方式，可在 Begin 之前修改
+	columns  []Column
+}
+
+func newBase() base {
+	return base{Renderer: DefaultRenderer()}
+}
+
+// null 判断第 i 列的值是否为 NULL
+func (b *base) null(i int, v interface{}) bool {
+	return b.Renderer.IsNull(b.columns[i], v)
+}
+
+// text 返回第 i 列的值在文本格式中的显示，NULL 显示为 Renderer.Null
+func (b *base) text(i int, v interface{}) string {
+	if b.null(i, v) {
+		return b.Renderer.Null
+	}
+	return b.Renderer.Text(b.columns[i], v)
+}
@@ -0,0 +1,89 @@
+package formatter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/godror/godror"
+)
+
+func TestRendererText(t *testing.T) {
+	shanghai := time.FixedZone("", 8*3600)
+	ts := time.Date(2024, 5, 1, 8, 30, 15, 123456000, shanghai)
+	custom := Renderer{
+		DateFormat:        "02/01/2006",
+		TimestampFormat:   "2006-01-02 15:04:05.000",
+		TimestampTZFormat: time.RFC3339Nano,
+	}
+
+	tests := []struct {
+		name     string
+		renderer Renderer
+		typ      string
+		value    interface{}
+		want     string
+	}{
+		{name: "DATE", typ: "DATE", value: ts.Truncate(time.Second), want: "2024-05-01 08:30:15"},
+		{name: "TIMESTAMP 小数秒", typ: "TIMESTAMP", value: ts, want: "2024-05-01 08:30:15.123456"},
+		{name: "TIMESTAMP 整秒", typ: "TIMESTAMP", value: ts.Truncate(time.Second), want: "2024-05-01 08:30:15"},
+		{name: "TIMESTAMP WITH TIME ZONE", typ: "TIMESTAMP WITH TIME ZONE", value: ts, want: "2024-05-01 08:30:15.123456 +08:00"},
+		{name: "自定义 DATE 格式", renderer: custom, typ: "DATE", value: ts, want: "01/05/2024"},
+		{name: "自定义 TIMESTAMP 格式", renderer: custom, typ: "TIMESTAMP", value: ts, want: "2024-05-01 08:30:15.123"},
+		{name: "自定义时区格式", renderer: custom, typ: "TIMESTAMP WITH LOCAL TIME ZONE", value: ts, want: "2024-05-01T08:30:15.123456+08:00"},
+		{name: "NUMBER 保持精度", typ: "NUMBER", value: godror.Number("0.10000000000000000001"), want: "0.10000000000000000001"},
+		{name: "BINARY_DOUBLE", typ: "BINARY_DOUBLE", value: 0.1, want: "0.1"},
+		{name: "BINARY_FLOAT", typ: "BINARY_FLOAT", value: float32(0.1), want: "0.1"},
+		{name: "RAW", typ: "RAW", value: []byte{0x01, 0xab, 0xff}, want: "01ABFF"},
+		{name: "BLOB", typ: "BLOB", value: LOB{Data: []byte{0xde, 0xad}, Truncated: true}, want: "DEAD" + truncatedSuffix},
+		{name: "CLOB", typ: "CLOB", value: LOB{Data: []byte("文本"), IsClob: true}, want: "文本"},
+		{name: "INTERVAL DAY TO SECOND", typ: "INTERVAL DAY TO SECOND", value: 26*time.Hour + 3*time.Minute + 4*time.Second + 500*time.Millisecond, want: "+1 02:03:04.5"},
+		{name: "负 INTERVAL DAY TO SECOND", typ: "INTERVAL DAY TO SECOND", value: -90 * time.Second, want: "-0 00:01:30"},
+		{name: "INTERVAL YEAR TO MONTH", typ: "INTERVAL YEAR TO MONTH", value: "1-2", want: "+1-02"},
+		{name: "负 INTERVAL YEAR TO MONTH", typ: "INTERVAL YEAR TO MONTH", value: "-1--2", want: "-1-02"},
+		{name: "XMLTYPE", typ: "XMLTYPE", value: "<a>1</a>", want: "<a>1</a>"},
+		{name: "未知类型", value: []byte("raw"), want: "raw"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.renderer.Text(Column{Name: "V", Type: tt.typ}, tt.value)
+			if got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRendererNull(t *testing.T) {
+	r := DefaultRenderer()
+	if !r.IsNull(Column{Type: "VARCHAR2"}, "") {
+		t.Error("VARCHAR2 的空字符串应该是 NULL")
+	}
+	if r.IsNull(Column{Type: "INTERVAL YEAR TO MONTH"}, "0-0") || r.IsNull(Column{}, "") {
+		t.Error("非字符类型的值不应该是 NULL")
+	}
+
+	var buf bytes.Buffer
+	f, err := NewWithRenderer(FormatVertical, &buf, Renderer{Null: "(null)"})
+	if err != nil {
+		t.Fatalf("NewWithRenderer 返回错误: %v", err)
+	}
+	columns := []Column{{Name: "A", Type: "VARCHAR2"}, {Name: "B", Type: "NUMBER"}}
+	if err := f.Begin(columns); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Row([]interface{}{"", nil}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.End(); err != nil {
+		t.Fatal(err)
+	}
+	want := "*************************** 1. 行 ***************************\n" +
+		"A: (null)\n" +
+		"B: (null)\n" +
+		"\n共返回 1 行数据\n"
+	if buf.String() != want {
+		t.Errorf("输出不正确:\n got: %q\nwant: %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,168 @@
+package formatter
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testDriver 返回固定结果集的数据库驱动，DSN 为 testResults 中的键
+type testDriver struct{}
+
+// testResult 测试驱动返回的结果集
+type testResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+var testResults = map[string]testResult{}
+
+func init() {
+	sql.Register("formatter-test", testDriver{})
+}
+
+func (testDriver) Open(name string) (driver.Conn, error) {
+	return testConn{result: testResults[name]}, nil
+}
+
+type testConn struct{ result testResult }
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) { return testStmt(c), nil }
+func (c testConn) Close() error                              { return nil }
+func (c testConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type testStmt struct{ result testResult }
+
+func (s testStmt) Close() error                                    { return nil }
+func (s testStmt) NumInput() int                                   { return -1 }
+func (s testStmt) Exec(args []driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &testRows{result: s.result}, nil
+}
+
+type testRows struct {
+	result testResult
+	pos    int
+}
+
+func (r *testRows) Columns() []string { return r.result.columns }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+// queryRows 使用测试驱动返回结果集
+func queryRows(t *testing.T, result testResult) *sql.Rows {
+	t.Helper()
+	testResults[t.Name()] = result
+	db, err := sql.Open("formatter-test", t.Name())
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+func TestWriteRows(t *testing.T) {
+	tests := []struct {
+		name          string
+		limits        Limits
+		want          string
+		wantCount     int64
+		wantTruncated bool
+	}{
+		{
+			name:      "不限制",
+			want:      "ID,DOC\n1,short\n2,a much longer document\n3,\n",
+			wantCount: 3,
+		},
+		{
+			name:          "最大行数",
+			limits:        Limits{MaxRows: 2},
+			want:          "ID,DOC\n1,short\n2,a much longer document\n",
+			wantCount:     2,
+			wantTruncated: true,
+		},
+		{
+			name:      "最大行数等于总行数",
+			limits:    Limits{MaxRows: 3},
+			want:      "ID,DOC\n1,short\n2,a much longer document\n3,\n",
+			wantCount: 3,
+		},
+		{
+			name:      "LOB字节数",
+			limits:    Limits{LOBBytes: 6},
+			want:      "ID,DOC\n1,short\n2,a much...(已截断)\n3,\n",
+			wantCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// LOB 读取器只能读取一次，每个子测试重新创建
+			rows := queryRows(t, testResult{
+				columns: []string{"ID", "DOC"},
+				rows: [][]driver.Value{
+					{int64(1), strings.NewReader("short")},
+					{int64(2), strings.NewReader("a much longer document")},
+					{int64(3), nil},
+				},
+			})
+			var buf bytes.Buffer
+			count, truncated, err := tt.limits.WriteRows(NewCSV(&buf), rows)
+			if err != nil {
+				t.Fatalf("WriteRows 返回错误: %v", err)
+			}
+			if count != tt.wantCount || truncated != tt.wantTruncated {
+				t.Errorf("WriteRows() = %d, %v, want %d, %v", count, truncated, tt.wantCount, tt.wantTruncated)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("输出不正确:\n got: %q\nwant: %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLOB(t *testing.T) {
+	lob, err := readLOB(strings.NewReader("0123456789"), 4)
+	if err != nil {
+		t.Fatalf("readLOB 返回错误: %v", err)
+	}
+	if string(lob.Data) != "0123" || !lob.Truncated {
+		t.Errorf("readLOB() = %q, %v, want \"0123\", true", lob.Data, lob.Truncated)
+	}
+
+	lob, err = readLOB(strings.NewReader("0123"), 4)
+	if err != nil {
+		t.Fatalf("readLOB 返回错误: %v", err)
+	}
+	if string(lob.Data) != "0123" || lob.Truncated {
+		t.Errorf("readLOB() = %q, %v, want \"0123\", false", lob.Data, lob.Truncated)
+	}
+
+	tests := map[string]string{
+		"abc":            "abc",
+		"ab\xe4\xb8":     "ab",
+		"ab\xe4":         "ab",
+		"ab\xe4\xb8\xad": "ab\xe4\xb8\xad",
+		"":               "",
+	}
+	for in, want := range tests {
+		if got := string(trimPartialRune([]byte(in))); got != want {
+			t.Errorf("trimPartialRune(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
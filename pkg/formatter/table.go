@@ -0,0 +1,206 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// cellReplacer 表格单元格中影响对齐的字符替换为空格
+var cellReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// tableSampleRows 表格根据前多少行计算列宽，之后的行直接输出
+const tableSampleRows = 100
+
+// Table 按显示宽度对齐的表格
+//
+// 列宽根据列名和前 tableSampleRows 行计算，这些行缓存到列宽确定后输出，之后的行
+// 逐行输出，不再缓存整个结果集；更宽的值超出列宽，不会截断。
+type Table struct {
+	base
+	w      io.Writer
+	rows   [][]string // 计算列宽前缓存的行
+	widths []int      // 已输出表头时为各列宽度
+	count  int
+}
+
+// NewTable 创建表格格式化器
+func NewTable(w io.Writer) *Table {
+	return &Table{base: newBase(), w: w}
+}
+
+func (t *Table) Begin(columns []Column) error {
+	t.columns = columns
+	return nil
+}
+
+func (t *Table) Row(values []interface{}) error {
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = cellReplacer.Replace(t.text(i, v))
+	}
+	t.count++
+	if t.widths != nil {
+		var sb strings.Builder
+		t.writeRow(&sb, row)
+		_, err := io.WriteString(t.w, sb.String())
+		return err
+	}
+	t.rows = append(t.rows, row)
+	if len(t.rows) < tableSampleRows {
+		return nil
+	}
+	return t.flush()
+}
+
+func (t *Table) End() error {
+	if t.widths == nil {
+		if err := t.flush(); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(t.w, "\n共返回 %d 行数据\n", t.count)
+	return err
+}
+
+// flush 根据缓存的行计算列宽，输出表头和缓存的行
+func (t *Table) flush() error {
+	t.widths = make([]int, len(t.columns))
+	for i, col := range t.columns {
+		t.widths[i] = DisplayWidth(col.Name)
+	}
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if w := DisplayWidth(cell); w > t.widths[i] {
+				t.widths[i] = w
+			}
+		}
+	}
+
+	var sb strings.Builder
+	header := make([]string, len(t.columns))
+	rule := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		header[i] = pad(col.Name, t.widths[i], numericTypes[col.Type])
+		rule[i] = strings.Repeat("-", t.widths[i])
+	}
+	writeLine(&sb, header, " | ")
+	writeLine(&sb, rule, "-+-")
+	for _, row := range t.rows {
+		t.writeRow(&sb, row)
+	}
+	t.rows = nil
+
+	_, err := io.WriteString(t.w, sb.String())
+	return err
+}
+
+// writeRow 按列宽输出一行数据
+func (t *Table) writeRow(sb *strings.Builder, row []string) {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		cells[i] = pad(cell, t.widths[i], numericTypes[t.columns[i].Type])
+	}
+	writeLine(sb, cells, " | ")
+}
+
+// writeLine 输出一行单元格，去掉行尾空白
+func writeLine(sb *strings.Builder, cells []string, sep string) {
+	sb.WriteString(strings.TrimRight(strings.Join(cells, sep), " "))
+	sb.WriteByte('\n')
+}
+
+// pad 按显示宽度补齐空格，right 为 true 时右对齐，超出宽度时原样返回
+func pad(s string, width int, right bool) string {
+	n := width - DisplayWidth(s)
+	if n <= 0 {
+		return s
+	}
+	spaces := strings.Repeat(" ", n)
+	if right {
+		return spaces + s
+	}
+	return s + spaces
+}
+
+// Vertical 每列单独一行输出，适合列较多或值较长的结果
+type Vertical struct {
+	base
+	w     io.Writer
+	width int
+	count int
+}
+
+// NewVertical 创建纵向格式化器
+func NewVertical(w io.Writer) *Vertical {
+	return &Vertical{base: newBase(), w: w}
+}
+
+func (v *Vertical) Begin(columns []Column) error {
+	v.columns = columns
+	for _, col := range columns {
+		if w := DisplayWidth(col.Name); w > v.width {
+			v.width = w
+		}
+	}
+	return nil
+}
+
+func (v *Vertical) Row(values []interface{}) error {
+	v.count++
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %d. 行 %s\n", strings.Repeat("*", 27), v.count, strings.Repeat("*", 27))
+	for i, value := range values {
+		fmt.Fprintf(&sb, "%s: %s\n", pad(v.columns[i].Name, v.width, true), v.text(i, value))
+	}
+	_, err := io.WriteString(v.w, sb.String())
+	return err
+}
+
+func (v *Vertical) End() error {
+	_, err := fmt.Fprintf(v.w, "\n共返回 %d 行数据\n", v.count)
+	return err
+}
+
+// DisplayWidth 返回字符串在终端中的显示宽度，中日韩文字和全角字符占两列
+func DisplayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case r == 0 || unicode.IsControl(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r):
+		case isWide(r):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+// wideRanges 东亚宽字符所在的码位范围
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},   // 谚文字母
+	{0x2E80, 0x303E},   // 中日韩部首、符号和标点
+	{0x3041, 0x33FF},   // 平假名、片假名、注音及中日韩兼容字符
+	{0x3400, 0x4DBF},   // 中日韩统一表意文字扩展 A
+	{0x4E00, 0x9FFF},   // 中日韩统一表意文字
+	{0xA000, 0xA4CF},   // 彝文
+	{0xAC00, 0xD7A3},   // 谚文音节
+	{0xF900, 0xFAFF},   // 中日韩兼容表意文字
+	{0xFE30, 0xFE4F},   // 中日韩兼容形式
+	{0xFF00, 0xFF60},   // 全角字符
+	{0xFFE0, 0xFFE6},   // 全角符号
+	{0x1F300, 0x1F64F}, // 表情符号
+	{0x1F900, 0x1F9FF}, // 补充表情符号
+	{0x20000, 0x3FFFD}, // 中日韩统一表意文字扩展 B 及之后
+}
+
+func isWide(r rune) bool {
+	for _, rg := range wideRanges {
+		if r >= rg[0] && r <= rg[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -2,14 +2,60 @@ package models
 
 import (
 	"fmt"
+	"strings"
 )
 
 // SQLError 自定义SQL错误
 type SQLError struct {
-	SQL     string
-	Message string
-	Line    int
-	File    string
+	SQL       string
+	Message   string
+	Line      int
+	File      string
+	StartLine int             // 语句起始行
+	EndLine   int             // 语句结束行
+	Position  *SourcePosition // 数据库报告的出错位置，未知时为 nil
+}
+
+// SQLWarning 不影响执行结果的警告，如 PL/SQL 编译警告
+type SQLWarning struct {
+	Message  string
+	Line     int
+	File     string
+	Position *SourcePosition // 警告在源文件中的位置，未知时为 nil
+}
+
+func (w *SQLWarning) String() string {
+	line := w.Line
+	if w.Position != nil {
+		line = w.Position.Line
+	}
+	return fmt.Sprintf("SQL警告 [%s:%d]: %s", w.File, line, w.Message)
+}
+
+// Snippet 返回警告所在行及指向警告列的标记，没有位置信息时返回空字符串
+func (w *SQLWarning) Snippet() string {
+	return (&SQLError{Position: w.Position}).Snippet()
+}
+
+// SourcePosition 出错位置在源文件中的行列
+type SourcePosition struct {
+	Line   int    // 行号
+	Column int    // 列号，从 1 开始，按字符计算
+	Source string // 该行的原始内容
+}
+
+// PositionError 携带源文件出错位置的错误
+type PositionError struct {
+	Err      error
+	Position SourcePosition
+}
+
+func (e *PositionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
 }
 
 func NewErrorResult(err error) *Result {
@@ -23,6 +69,34 @@ func (e *SQLError) Error() string {
 	return fmt.Sprintf("SQL错误 [%s:%d]: %s\nSQL: %s", e.File, e.Line, e.Message, e.SQL)
 }
 
+// Snippet 返回出错行及指向出错列的标记，没有位置信息时返回空字符串
+func (e *SQLError) Snippet() string {
+	if e.Position == nil || e.Position.Column < 1 {
+		return ""
+	}
+
+	gutter := fmt.Sprintf("%d", e.Position.Line)
+	source := strings.TrimRight(e.Position.Source, "\r\n")
+
+	// 保留制表符以便标记与原文对齐
+	var marker strings.Builder
+	for i, r := range []rune(source) {
+		if i >= e.Position.Column-1 {
+			break
+		}
+		if r == '\t' {
+			marker.WriteRune('\t')
+		} else {
+			marker.WriteRune(' ')
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "  %s | %s\n", gutter, source)
+	fmt.Fprintf(&sb, "  %s | %s^\n", strings.Repeat(" ", len(gutter)), marker.String())
+	return sb.String()
+}
+
 // NewSQLError 创建新的SQL错误
 func NewSQLError(sql string, message string, line int, file string) *SQLError {
 	return &SQLError{
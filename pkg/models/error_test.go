@@ -93,3 +93,17 @@ func TestNewSQLError(t *testing.T) {
 		t.Errorf("Expected File to be %s, got %s", file, err.File)
 	}
 }
+
+func TestSQLError_Snippet(t *testing.T) {
+	err := &SQLError{
+		Position: &SourcePosition{Line: 7, Column: 4, Source: "\tx := y;"},
+	}
+	want := "  7 | \tx := y;\n    | \t  ^\n"
+	if got := err.Snippet(); got != want {
+		t.Errorf("Snippet() = %q, want %q", got, want)
+	}
+
+	if got := (&SQLError{}).Snippet(); got != "" {
+		t.Errorf("Snippet() without position = %q, want empty", got)
+	}
+}
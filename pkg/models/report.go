@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// DatabaseResult 脚本在单个数据库上的执行结果
+type DatabaseResult struct {
+	Database string
+	Result   *Result
+}
+
+// Succeeded 判断脚本在该数据库上是否执行成功
+//
+// 通过 WHENEVER/EXIT 以非 0 退出码退出时为失败；退出码为 0 时（如 WHENEVER SQLERROR
+// CONTINUE 之后的 EXIT）仍按失败的语句判断。
+func (d DatabaseResult) Succeeded() bool {
+	if d.Result.Exited && d.Result.ExitCode != 0 {
+		return false
+	}
+	return d.Result.Failed == 0 && len(d.Result.Errors) == 0
+}
+
+// Report 同一脚本在多个数据库上执行的汇总结果，按执行批次和数据库名称排序
+type Report struct {
+	Databases []DatabaseResult
+	Pending   []string // 因之前的批次失败或未确认而未执行的数据库
+	Duration  time.Duration
+}
+
+// Failed 返回执行失败的数据库
+func (r *Report) Failed() []string {
+	var names []string
+	for _, d := range r.Databases {
+		if !d.Succeeded() {
+			names = append(names, d.Database)
+		}
+	}
+	return names
+}
+
+// ExitCode 返回整体退出码：全部执行成功时为 0，否则为第一个失败数据库中脚本指定的退出码，
+// 未指定或仅有未执行的数据库时为 1
+func (r *Report) ExitCode() int {
+	for _, d := range r.Databases {
+		if d.Succeeded() {
+			continue
+		}
+		if d.Result.Exited && d.Result.ExitCode != 0 {
+			return d.Result.ExitCode
+		}
+		return 1
+	}
+	if len(r.Pending) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Print 打印汇总结果
+func (r *Report) Print() {
+	r.Fprint(os.Stdout)
+}
+
+// Fprint 将汇总结果写入 w，失败的数据库附带第一条错误
+func (r *Report) Fprint(w io.Writer) {
+	fmt.Fprintf(w, "\n汇总结果:\n")
+	for _, d := range r.Databases {
+		res := d.Result
+		status := "成功"
+		if !d.Succeeded() {
+			status = "失败"
+		}
+		if res.Exited && res.ExitCode != 0 {
+			status += fmt.Sprintf("（退出码 %d）", res.ExitCode)
+		}
+		fmt.Fprintf(w, "%s: %s, 成功: %d, 失败: %d", d.Database, status, res.Success, res.Failed)
+		if res.Skipped > 0 {
+			fmt.Fprintf(w, ", 跳过: %d", res.Skipped)
+		}
+		fmt.Fprintf(w, ", 执行时间: %.2f秒\n", res.Duration.Seconds())
+		if !d.Succeeded() && len(res.Errors) > 0 {
+			fmt.Fprintf(w, "  %s\n", res.Errors[0].Message)
+		}
+	}
+
+	if len(r.Pending) > 0 {
+		fmt.Fprintf(w, "未执行: %s\n", strings.Join(r.Pending, ", "))
+	}
+
+	failed := r.Failed()
+	fmt.Fprintf(w, "\n数据库总数: %d\n", len(r.Databases)+len(r.Pending))
+	fmt.Fprintf(w, "成功: %d\n", len(r.Databases)-len(failed))
+	fmt.Fprintf(w, "失败: %d\n", len(failed))
+	if len(r.Pending) > 0 {
+		fmt.Fprintf(w, "未执行: %d\n", len(r.Pending))
+	}
+	fmt.Fprintf(w, "总执行时间: %.2f秒\n", r.Duration.Seconds())
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport_ExitCode(t *testing.T) {
+	success := NewResult()
+	success.AddSuccess()
+
+	failed := NewResult()
+	failed.AddError(SQLTask{SQL: "DROP TABLE missing"}, errors.New("ORA-00942: table or view does not exist"))
+
+	exited := NewResult()
+	exited.Exited = true
+	exited.ExitCode = 3
+
+	exitedOK := NewResult()
+	exitedOK.Exited = true
+
+	// WHENEVER SQLERROR CONTINUE 时语句失败后脚本仍以 EXIT 0 结束
+	exitedAfterFailure := NewResult()
+	exitedAfterFailure.AddError(SQLTask{SQL: "DROP TABLE missing"}, errors.New("ORA-00942: table or view does not exist"))
+	exitedAfterFailure.Exited = true
+
+	tests := []struct {
+		name   string
+		report Report
+		want   int
+		failed []string
+	}{
+		{
+			name: "全部成功",
+			report: Report{Databases: []DatabaseResult{
+				{Database: "a", Result: success},
+				{Database: "b", Result: exitedOK},
+			}},
+			want: 0,
+		},
+		{
+			name: "执行失败",
+			report: Report{Databases: []DatabaseResult{
+				{Database: "a", Result: success},
+				{Database: "b", Result: failed},
+			}},
+			want:   1,
+			failed: []string{"b"},
+		},
+		{
+			name: "连接失败",
+			report: Report{Databases: []DatabaseResult{
+				{Database: "a", Result: NewErrorResult(errors.New("创建连接池失败"))},
+			}},
+			want:   1,
+			failed: []string{"a"},
+		},
+		{
+			name: "脚本退出码",
+			report: Report{Databases: []DatabaseResult{
+				{Database: "a", Result: success},
+				{Database: "b", Result: exited},
+				{Database: "c", Result: failed},
+			}},
+			want:   3,
+			failed: []string{"b", "c"},
+		},
+		{
+			name: "EXIT 前有失败的语句",
+			report: Report{Databases: []DatabaseResult{
+				{Database: "a", Result: success},
+				{Database: "b", Result: exitedAfterFailure},
+			}},
+			want:   1,
+			failed: []string{"b"},
+		},
+		{
+			name: "已取消",
+			report: Report{
+				Databases: []DatabaseResult{{Database: "a", Result: success}},
+				Pending:   []string{"b"},
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.report.ExitCode(); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+			if got := strings.Join(tt.report.Failed(), ","); got != strings.Join(tt.failed, ",") {
+				t.Errorf("Failed() = %s, want %s", got, strings.Join(tt.failed, ","))
+			}
+		})
+	}
+}
+
+func TestReport_Fprint(t *testing.T) {
+	success := NewResult()
+	success.AddSuccess()
+	success.Duration = time.Second
+
+	report := Report{
+		Databases: []DatabaseResult{
+			{Database: "prod-east", Result: success},
+			{Database: "prod-west", Result: NewErrorResult(errors.New("创建连接池失败: ORA-12541"))},
+		},
+		Pending:  []string{"prod-north", "prod-south"},
+		Duration: 2 * time.Second,
+	}
+
+	var buf bytes.Buffer
+	report.Fprint(&buf)
+	output := buf.String()
+
+	expectedParts := []string{
+		"prod-east: 成功, 成功: 1, 失败: 0, 执行时间: 1.00秒\n",
+		"prod-west: 失败, 成功: 0, 失败: 0, 执行时间: 0.00秒\n  创建连接池失败: ORA-12541\n",
+		"未执行: prod-north, prod-south\n",
+		"数据库总数: 4\n成功: 1\n失败: 1\n未执行: 2\n",
+		"总执行时间: 2.00秒",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(output, part) {
+			t.Errorf("missing expected output: %q\n%s", part, output)
+		}
+	}
+}
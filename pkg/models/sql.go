@@ -1,7 +1,10 @@
 package models
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sync"
 	"time"
 )
@@ -10,28 +13,93 @@ import (
 type SQLType string
 
 const (
-	SQLTypeQuery SQLType = "query"
-	SQLTypeExec  SQLType = "exec"
-	SQLTypePLSQL SQLType = "plsql"
+	SQLTypeQuery   SQLType = "query"   // 返回结果集的查询：SELECT、WITH
+	SQLTypeExec    SQLType = "exec"    // 其他语句，如 ALTER SESSION、ALTER SYSTEM
+	SQLTypePLSQL   SQLType = "plsql"   // PL/SQL 程序单元：过程、函数、包、触发器、类型等
+	SQLTypeCommand SQLType = "command" // SQL*Plus 客户端命令，由执行器自身处理
+	SQLTypeDML     SQLType = "dml"     // INSERT、UPDATE、DELETE、MERGE、CALL 等
+	SQLTypeDDL     SQLType = "ddl"     // CREATE、ALTER、DROP、TRUNCATE 等
+	SQLTypeDCL     SQLType = "dcl"     // GRANT、REVOKE
+	SQLTypeTCL     SQLType = "tcl"     // COMMIT、ROLLBACK、SAVEPOINT、SET TRANSACTION
+	SQLTypeBlock   SQLType = "block"   // 匿名 PL/SQL 块：BEGIN、DECLARE
 )
 
+// IsPLSQL 判断是否为 PL/SQL 程序单元或匿名块
+func (t SQLType) IsPLSQL() bool {
+	return t == SQLTypePLSQL || t == SQLTypeBlock
+}
+
 // SQLTask 表示单个SQL任务
 type SQLTask struct {
-	SQL      string
-	Type     SQLType
-	LineNum  int
-	Filename string
+	Index      int // 语句在脚本中的序号，从 1 开始，SQL*Plus 命令为 0
+	SQL        string
+	Type       SQLType
+	Verb       string // 语句动作，如 SELECT、INSERT、CREATE TABLE、ALTER SESSION
+	Object     string // 目标对象，如 APP.USERS，无法确定时为空
+	LineNum    int    // 语句结束符所在行
+	StartLine  int    // 语句起始行
+	EndLine    int    // 语句最后一个有效字符所在行
+	Text       string // 源文件中的原始文本，未经变量替换和格式化
+	Filename   string
+	Directives Directives // 语句前 -- @runner: 注释指定的执行选项
+}
+
+// Directives 通过 -- @runner: 注释为单条语句指定的执行选项
+type Directives struct {
+	Timeout    time.Duration // 执行超时，0 表示使用全局配置
+	Retries    *int          // 失败后的重试次数，nil 表示使用默认值
+	Ignore     []int         // 视为成功的 Oracle 错误码，如 955 表示 ORA-00955
+	Serial     bool          // 单独执行，不与其他语句并行
+	Idempotent bool          // 重复执行结果相同，连接中断后可以安全重试
+	Tags       []string      // 语句标签
+	Name       string        // 语句名称，用作查询结果文件名
+	Spool      bool          // 将查询结果写入单独的文件
+	MaxRows    *int          // 查询最多输出的行数，nil 表示使用全局配置，0 表示不限制
+}
+
+// Ignores 判断错误码是否在忽略列表中
+func (d Directives) Ignores(code int) bool {
+	for _, c := range d.Ignore {
+		if c == code {
+			return true
+		}
+	}
+	return false
 }
 
 // Result SQL执行结果
 type Result struct {
-	mu        sync.Mutex // 添加互斥锁
-	Success   int
-	Failed    int
-	Errors    []SQLError
-	Duration  time.Duration
-	StartTime time.Time
-	EndTime   time.Time
+	mu         sync.Mutex // 添加互斥锁
+	Success    int
+	Failed     int
+	Skipped    int  // 因 WHENEVER/EXIT 提前退出而未执行的任务数
+	Exited     bool // 是否由 WHENEVER/EXIT 结束执行
+	ExitCode   int  // WHENEVER/EXIT 指定的退出码
+	Retries    int  // 所有语句的重试次数之和
+	Errors     []SQLError
+	Warnings   []SQLWarning      // PL/SQL 编译警告等不影响执行结果的警告
+	Statements []StatementResult // 每条已执行语句的结果，按脚本顺序排列
+	Duration   time.Duration
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+// StatementResult 单条语句的执行结果
+type StatementResult struct {
+	Index        int // 语句在脚本中的序号
+	Type         SQLType
+	File         string
+	StartLine    int
+	EndLine      int
+	RowsAffected int64 // DML 影响的行数
+	RowsReturned int64 // 查询返回的行数
+	Truncated    bool  // 查询达到最大行数，剩余的行未读取
+	Duration     time.Duration
+	Name         string    // @runner:name 指定的语句名称
+	Retries      int       // 重试次数，不含首次执行
+	Output       []string  // DBMS_OUTPUT 输出的内容
+	SpoolFile    string    // 查询结果写入的文件路径
+	Error        *SQLError // 执行失败时的错误
 }
 
 // NewResult 创建新的结果对象
@@ -46,12 +114,25 @@ func (r *Result) AddError(task SQLTask, err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.Failed++
-	r.Errors = append(r.Errors, SQLError{
-		SQL:     task.SQL,
-		Message: err.Error(),
-		Line:    task.LineNum,
-		File:    task.Filename,
-	})
+	r.Errors = append(r.Errors, newSQLError(task, err))
+}
+
+// newSQLError 根据任务和执行错误生成错误信息，错误携带出错位置时一并记录
+func newSQLError(task SQLTask, err error) SQLError {
+	sqlErr := SQLError{
+		SQL:       task.SQL,
+		Message:   err.Error(),
+		Line:      task.LineNum,
+		File:      task.Filename,
+		StartLine: task.StartLine,
+		EndLine:   task.EndLine,
+	}
+	var posErr *PositionError
+	if errors.As(err, &posErr) {
+		pos := posErr.Position
+		sqlErr.Position = &pos
+	}
+	return sqlErr
 }
 
 // AddSuccess 添加成功计数
@@ -61,6 +142,70 @@ func (r *Result) AddSuccess() {
 	r.Success++
 }
 
+// AddWarning 添加警告信息
+func (r *Result) AddWarning(task SQLTask, message string, pos *SourcePosition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, SQLWarning{
+		Message:  message,
+		Line:     task.LineNum,
+		File:     task.Filename,
+		Position: pos,
+	})
+}
+
+// AddStatement 记录单条语句的执行结果，err 不为 nil 时同时计入失败
+func (r *Result) AddStatement(task SQLTask, stmt StatementResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stmt.Index = task.Index
+	stmt.Type = task.Type
+	stmt.File = task.Filename
+	stmt.StartLine = task.StartLine
+	stmt.EndLine = task.EndLine
+	stmt.Name = task.Directives.Name
+	r.Retries += stmt.Retries
+	if err != nil {
+		r.Failed++
+		sqlErr := newSQLError(task, err)
+		r.Errors = append(r.Errors, sqlErr)
+		stmt.Error = &sqlErr
+	} else {
+		r.Success++
+	}
+	r.Statements = append(r.Statements, stmt)
+}
+
+// AddSkipped 添加跳过计数
+func (r *Result) AddSkipped(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Skipped += n
+}
+
+// Merge 合并另一个结果的计数和错误
+func (r *Result) Merge(other *Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Success += other.Success
+	r.Failed += other.Failed
+	r.Skipped += other.Skipped
+	r.Retries += other.Retries
+	r.Errors = append(r.Errors, other.Errors...)
+	r.Warnings = append(r.Warnings, other.Warnings...)
+	r.Statements = append(r.Statements, other.Statements...)
+}
+
+// LastError 返回最后一个错误，没有错误时返回 nil
+func (r *Result) LastError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return &r.Errors[len(r.Errors)-1]
+}
+
 // Finish 完成执行
 func (r *Result) Finish() {
 	r.EndTime = time.Now()
@@ -68,16 +213,43 @@ func (r *Result) Finish() {
 
 // Print 打印结果
 func (r *Result) Print() {
-	fmt.Printf("\n执行结果:\n")
-	fmt.Printf("总语句数: %d\n", r.Success+r.Failed)
-	fmt.Printf("成功: %d\n", r.Success)
-	fmt.Printf("失败: %d\n", r.Failed)
-	fmt.Printf("总执行时间: %.2f秒\n", r.Duration.Seconds())
+	r.Fprint(os.Stdout)
+}
+
+// Fprint 将执行结果写入 w
+func (r *Result) Fprint(w io.Writer) {
+	fmt.Fprintf(w, "\n执行结果:\n")
+	fmt.Fprintf(w, "总语句数: %d\n", r.Success+r.Failed)
+	fmt.Fprintf(w, "成功: %d\n", r.Success)
+	fmt.Fprintf(w, "失败: %d\n", r.Failed)
+	if r.Skipped > 0 {
+		fmt.Fprintf(w, "跳过: %d\n", r.Skipped)
+	}
+	if r.Retries > 0 {
+		fmt.Fprintf(w, "重试: %d\n", r.Retries)
+	}
+	if len(r.Warnings) > 0 {
+		fmt.Fprintf(w, "警告: %d\n", len(r.Warnings))
+	}
+	fmt.Fprintf(w, "总执行时间: %.2f秒\n", r.Duration.Seconds())
+
+	if len(r.Warnings) > 0 {
+		fmt.Fprintf(w, "\n警告详情:\n")
+		for i, warning := range r.Warnings {
+			fmt.Fprintf(w, "%d. %s\n", i+1, warning.String())
+			if snippet := warning.Snippet(); snippet != "" {
+				fmt.Fprint(w, snippet)
+			}
+		}
+	}
 
 	if r.Failed > 0 {
-		fmt.Printf("\n错误详情:\n")
+		fmt.Fprintf(w, "\n错误详情:\n")
 		for i, err := range r.Errors {
-			fmt.Printf("%d. %s\n", i+1, err.Error())
+			fmt.Fprintf(w, "%d. %s\n", i+1, err.Error())
+			if snippet := err.Snippet(); snippet != "" {
+				fmt.Fprint(w, snippet)
+			}
 		}
 	}
 }
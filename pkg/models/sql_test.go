@@ -68,6 +68,65 @@ func TestResult_AddSuccess(t *testing.T) {
 	}
 }
 
+func TestResult_AddStatement(t *testing.T) {
+	result := NewResult()
+	task := SQLTask{Index: 2, SQL: "UPDATE t SET a = 1", Type: SQLTypeDML, Filename: "test.sql", StartLine: 3, EndLine: 4, LineNum: 4}
+	result.AddStatement(task, StatementResult{RowsAffected: 5, Duration: time.Second, Retries: 1, Output: []string{"hello"}}, nil)
+
+	other := NewResult()
+	other.AddStatement(SQLTask{Index: 3, Filename: "test.sql", LineNum: 6}, StatementResult{}, errors.New("ORA-00942"))
+	result.Merge(other)
+
+	if result.Success != 1 || result.Failed != 1 {
+		t.Errorf("Expected 1 success and 1 failure, got %d and %d", result.Success, result.Failed)
+	}
+	if result.Retries != 1 {
+		t.Errorf("Expected 1 retry in total, got %d", result.Retries)
+	}
+	if len(result.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(result.Statements))
+	}
+
+	stmt := result.Statements[0]
+	if stmt.Index != 2 || stmt.Type != SQLTypeDML || stmt.File != "test.sql" || stmt.StartLine != 3 || stmt.EndLine != 4 {
+		t.Errorf("Statement should copy task details, got %+v", stmt)
+	}
+	if stmt.RowsAffected != 5 || stmt.Retries != 1 || len(stmt.Output) != 1 || stmt.Error != nil {
+		t.Errorf("Unexpected statement result: %+v", stmt)
+	}
+
+	failed := result.Statements[1]
+	if failed.Error == nil || failed.Error.Message != "ORA-00942" {
+		t.Errorf("Expected statement error, got %+v", failed.Error)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Line != 6 {
+		t.Errorf("Failed statement should also be recorded in Errors, got %+v", result.Errors)
+	}
+}
+
+func TestResult_AddWarning(t *testing.T) {
+	result := NewResult()
+	task := SQLTask{Filename: "test.sql", LineNum: 12}
+	result.AddWarning(task, "PLW-06002: Unreachable code", &SourcePosition{Line: 8, Column: 5, Source: "    NULL;"})
+	result.AddWarning(task, "PLW-05018: unit omitted optional AUTHID clause", nil)
+
+	if len(result.Warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %d", len(result.Warnings))
+	}
+	if result.Failed != 0 {
+		t.Errorf("Warnings should not count as failures, got %d", result.Failed)
+	}
+	if got := result.Warnings[0].String(); !strings.Contains(got, "test.sql:8") {
+		t.Errorf("Warning should use source position, got %q", got)
+	}
+	if got := result.Warnings[1].String(); !strings.Contains(got, "test.sql:12") {
+		t.Errorf("Warning should fall back to statement line, got %q", got)
+	}
+	if result.Warnings[1].Snippet() != "" {
+		t.Error("Warning without position should have empty snippet")
+	}
+}
+
 func TestResult_Finish(t *testing.T) {
 	result := NewResult()
 	time.Sleep(10 * time.Millisecond) // 确保有可测量的持续时间
@@ -110,6 +169,34 @@ func TestResult_Print(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name: "错误位置",
+			setup: func(r *Result) {
+				r.AddError(SQLTask{
+					SQL:       "SELECT *\n  FROM missing",
+					LineNum:   13,
+					StartLine: 12,
+					EndLine:   13,
+					Filename:  "test.sql",
+				}, &PositionError{
+					Err:      errors.New("ORA-00942: table or view does not exist"),
+					Position: SourcePosition{Line: 13, Column: 8, Source: "  FROM missing"},
+				})
+			},
+			verify: func(output string) error {
+				expectedParts := []string{
+					"ORA-00942",
+					"  13 |   FROM missing\n",
+					"     |        ^\n",
+				}
+				for _, part := range expectedParts {
+					if !strings.Contains(output, part) {
+						return errors.New("missing expected output: " + part)
+					}
+				}
+				return nil
+			},
+		},
 		{
 			name: "包含错误",
 			setup: func(r *Result) {